@@ -14,33 +14,10 @@ func Attach() *cli.Command {
 	socketName, socketPath := tmux.GetDefaultSocket()
 
 	return &cli.Command{
-		Name:    "attach",
-		Aliases: []string{"a"},
-		Usage:   "Attach to existing tmux server and session",
-		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
-			{
-				Flags: [][]cli.Flag{
-					{
-						&cli.StringFlag{
-							Name:        "name",
-							Aliases:     []string{"n"},
-							Value:       socketName,
-							Usage:       "tmux server socket name",
-							Destination: &socketName,
-						},
-					},
-					{
-						&cli.StringFlag{
-							Name:        "path",
-							Aliases:     []string{"p"},
-							Value:       socketPath,
-							Usage:       "tmux server socket path",
-							Destination: &socketPath,
-						},
-					},
-				},
-			},
-		},
+		Name:                   "attach",
+		Aliases:                []string{"a"},
+		Usage:                  "Attach to existing tmux server and session",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:        "target",
@@ -50,11 +27,11 @@ func Attach() *cli.Command {
 			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			server := tmux.NewServer(socketName, socketPath)
+			server := flagServer(cmd, socketName, socketPath)
 
-			_, _, err := server.Attach(target)
+			_, _, err := server.Attach(ctx, target)
 			if err != nil {
-				cli.Exit(fmt.Errorf("error while attaching to server with socket name '%s' and socket path '%s': %w", server.SocketName, server.SocketPath, err), 1)
+				return exitError(fmt.Sprintf("error while attaching to server with socket name '%s' and socket path '%s'", server.SocketName, server.SocketPath), err)
 			}
 			return nil
 		},
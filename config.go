@@ -0,0 +1,690 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+
+	"github.com/winter-again/flow/internal/config"
+	"github.com/winter-again/flow/internal/finder"
+	"github.com/winter-again/flow/internal/xdg"
+)
+
+func Config() *cli.Command {
+	var force, effective bool
+
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Create, show, edit and check flow's config",
+		Commands: []*cli.Command{
+			{
+				Name:  "init",
+				Usage: "Write a config file with every setting at its default, commented out",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "force",
+						Aliases:     []string{"f"},
+						Usage:       "Overwrite an existing config file",
+						Destination: &force,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path, err := configPath(cmd.String("config"))
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					if _, err := os.Stat(path); err == nil && !force {
+						return cli.Exit(fmt.Sprintf("%s already exists; edit it with `flow config edit` or overwrite it with --force", path), 1)
+					}
+					if err := writeDefaultConfig(path); err != nil {
+						return cli.Exit(err, 1)
+					}
+					fmt.Println(path)
+					return nil
+				},
+			},
+			{
+				Name:  "show",
+				Usage: "Print the config file, or with --effective every setting and where its value came from",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "effective",
+						Aliases:     []string{"e"},
+						Usage:       "Print the defaults merged with the config file and FLOW_* env vars",
+						Destination: &effective,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := loadConfig(cmd.String("config"), cmd.String("profile")); err != nil {
+						return cli.Exit(err, 1)
+					}
+					if effective {
+						fmt.Print(effectiveConfig())
+						return nil
+					}
+					if configFile == "" {
+						return cli.Exit("no config file; create one with `flow config init`", 1)
+					}
+					data, err := os.ReadFile(configFile)
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					os.Stdout.Write(data)
+					return nil
+				},
+			},
+			{
+				Name:  "path",
+				Usage: "Print the path of the config file, whether or not it exists",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path, err := configPath(cmd.String("config"))
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					fmt.Println(path)
+					return nil
+				},
+			},
+			{
+				Name:  "edit",
+				Usage: "Open the config file in $VISUAL or $EDITOR, creating it if needed, and check it once saved",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return editConfig(ctx, cmd.String("config"), cmd.String("profile"))
+				},
+			},
+			{
+				Name:  "validate",
+				Usage: "Check the config file, its profiles and FLOW_* env vars for unknown keys and invalid values",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := loadConfig(cmd.String("config"), cmd.String("profile")); err != nil {
+						return cli.Exit(err, 1)
+					}
+					if err := validateProfiles(cmd.String("config"), cmd.String("profile")); err != nil {
+						return cli.Exit(err, 1)
+					}
+					if configFile == "" {
+						fmt.Println("no config file; the defaults are valid")
+						return nil
+					}
+					fmt.Printf("%s is valid\n", configFile)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// writeDefaultConfig writes the defaults, commented out, to a new config file
+func writeDefaultConfig(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("couldn't create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(defaultConfig()), 0o644); err != nil {
+		return fmt.Errorf("couldn't write config file: %w", err)
+	}
+	return nil
+}
+
+// defaultConfig renders the defaults as a config file: each setting commented out, below its doc
+func defaultConfig() string {
+	var b strings.Builder
+	b.WriteString("# flow config, written by `flow config init`. Every setting is at its default;\n")
+	b.WriteString("# uncomment the ones to change. `flow config show --effective` prints what's in use.\n")
+
+	table := ""
+	for _, setting := range defaults {
+		t, name := splitKey(setting.key)
+		if t != table {
+			table = t
+			fmt.Fprintf(&b, "\n[%s]\n", table)
+		} else {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %s\n# %s = %s\n", setting.doc, name, tomlValue(setting.value))
+	}
+	b.WriteString(tablesExample)
+	return b.String()
+}
+
+// tablesExample documents the lists of tables, which have no defaults
+const tablesExample = `
+# Roots with their own settings, e.g. ~/code/<org>/<repo>
+# [[find.roots]]
+# path = "~/code"
+# max_depth = 2
+# exclude = ["archive"] # added to find.exclude
+
+# Sessions created in matching dirs; the same keys, besides match, go in .flow.toml files
+# [[project]]
+# match = ["~/code/api", "~/work/*"]
+# name = "api"
+# env = { PORT = "8080" }
+# commands = ["git pull"]
+# hooks = { create = ["docker compose up -d"], switch = [] }
+# [[project.window]]
+# name = "editor"
+# [[project.window.pane]]
+# cmd = "nvim"
+
+# Settings used instead of the ones above with --profile or $FLOW_PROFILE, e.g. work. A
+# profile can set flow.init_session_name, flow.socket, [fzf-tmux] and [find]
+# [profile.work.flow]
+# socket = "work"
+# [profile.work.find]
+# dirs = ["~/work"]
+`
+
+// effectiveConfig renders every setting in use, as TOML, with where its value came from
+func effectiveConfig() string {
+	var lines map[string]int
+	if configFile != "" {
+		lines, _ = config.Lines(configFile)
+	}
+
+	keys := make([]string, 0, len(defaults))
+	for _, setting := range defaults {
+		keys = append(keys, setting.key)
+	}
+	var tables []string
+	for _, key := range k.Keys() {
+		// NOTE: the selected profile is already merged into the settings it overrides
+		if slices.Contains(keys, key) || strings.HasPrefix(key, "profile.") {
+			continue
+		}
+		if isPlainValue(k.Get(key)) {
+			keys = append(keys, key)
+		} else {
+			tables = append(tables, key)
+		}
+	}
+	// NOTE: keys without defaults, e.g. misspelled ones, go with the rest of their table
+	order := make(map[string]int)
+	for _, key := range keys {
+		table, _ := splitKey(key)
+		if _, ok := order[table]; !ok {
+			order[table] = len(order)
+		}
+	}
+	slices.SortStableFunc(keys, func(a, b string) int {
+		ta, _ := splitKey(a)
+		tb, _ := splitKey(b)
+		return order[ta] - order[tb]
+	})
+
+	var b strings.Builder
+	if configProfile != "" {
+		fmt.Fprintf(&b, "# profile %s\n\n", configProfile)
+	}
+	table := ""
+	for i, key := range keys {
+		t, name := splitKey(key)
+		if t != table || i == 0 {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			table = t
+			if table != "" {
+				fmt.Fprintf(&b, "[%s]\n", table)
+			}
+		}
+		fmt.Fprintf(&b, "%s = %s # %s\n", name, tomlValue(k.Get(key)), valueSource(key, lines))
+	}
+
+	// NOTE: lists of tables only come from the config file
+	for _, key := range tables {
+		fmt.Fprintf(&b, "\n# %s\n", valueSource(key, lines))
+		writeTables(&b, key, k.Get(key))
+	}
+	return b.String()
+}
+
+// writeTables renders a list of tables, e.g. [[project]], along with the lists of tables
+// nested in them, e.g. [[project.window]]
+func writeTables(b *strings.Builder, key string, value any) {
+	items, ok := value.([]any)
+	if !ok {
+		fmt.Fprintf(b, "%s = %s\n", key, tomlValue(value))
+		return
+	}
+	for _, item := range items {
+		table, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(b, "[[%s]]\n", key)
+
+		names := slices.Sorted(maps.Keys(table))
+		var nested []string
+		for _, name := range names {
+			if isPlainValue(table[name]) {
+				fmt.Fprintf(b, "%s = %s\n", name, tomlValue(table[name]))
+			} else {
+				nested = append(nested, name)
+			}
+		}
+		for _, name := range nested {
+			writeTables(b, key+"."+name, table[name])
+		}
+	}
+}
+
+// splitKey splits a key into its table and its name in the table
+func splitKey(key string) (string, string) {
+	i := strings.LastIndexByte(key, '.')
+	if i < 0 {
+		return "", key
+	}
+	return key[:i], key[i+1:]
+}
+
+// tomlValue renders a config value as TOML
+func tomlValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = strconv.Quote(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = tomlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(value)
+}
+
+// editConfig opens the config file in the user's editor, writing the defaults to it first if
+// it doesn't exist, and checks it once the editor exits. While it's broken, the user can go
+// back and fix it
+func editConfig(ctx context.Context, path string, profile string) error {
+	path, err := configPath(path)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := writeDefaultConfig(path); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		// NOTE: through the shell, since editors are often set with args, e.g. "code --wait"
+		edit := exec.CommandContext(ctx, "sh", "-c", editor+` "$1"`, "sh", path)
+		edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := edit.Run(); err != nil {
+			return cli.Exit(fmt.Sprintf("error while running %s: %v", editor, err), 1)
+		}
+
+		err := loadConfig(path, profile)
+		if err == nil {
+			err = validateProfiles(path, profile)
+		}
+		if err == nil {
+			return nil
+		}
+
+		fmt.Fprintln(os.Stderr, err)
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return cli.Exit("", 1)
+		}
+		fmt.Fprint(os.Stderr, "Edit again? [Y/n] ")
+		answer, _ := stdin.ReadString('\n')
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "n") {
+			return cli.Exit("", 1)
+		}
+	}
+}
+
+// valueSource returns where the value of a key came from, like configSource, or "default"
+// if neither an env var nor the config file sets it
+func valueSource(key string, lines map[string]int) string {
+	// NOTE: configSource falls back to the table holding a key, which doesn't mean it's set
+	_, inFile := lines[key]
+	_, inProfile := profileKey(key)
+	if !inFile && !inProfile && !hasEnv(envName(key)) {
+		return "default"
+	}
+	return configSource(key, lines)
+}
+
+// setting is a config key with its default and what it's for
+type setting struct {
+	key   string
+	value any
+	doc   string
+}
+
+// defaults are what flow runs on without a config file. `flow config init` writes them
+// out with their docs, so this is where settings are documented
+var defaults = []setting{
+	{"flow.init_session_name", "0", "Name of the first session of a server started by flow"},
+	{"flow.picker", "fzf-tmux", `Picker of flow switch: "fzf-tmux", "fzf", "skim", "dmenu" or "builtin"`},
+	{"flow.sort", "frecency", `Order of sessions and dirs: "alpha", "recency" or "frecency"`},
+	{"flow.timeout", "5s", `How long to wait on each tmux command, "0" to wait forever`},
+	{"flow.trust", []string{}, "Dirs or globs whose .flow.toml files, and those of their subdirs, are loaded"},
+	{"flow.socket", "", `Server used without --name or --path: a socket name, e.g. "work", or path; "" for tmux's default`},
+	// TODO: should allow user to config this from fzf-tmux instead?
+	{"fzf-tmux.width", "80%", "Width of the picker popup, in cells or percent"},
+	{"fzf-tmux.length", "60%", "Height of the picker popup, in cells or percent"},
+	{"fzf-tmux.border", "rounded", "Border style of the picker"},
+	{"fzf-tmux.preview_size", "60%", "Share of the picker taken by the preview"},
+	{"fzf-tmux.preview_border", "rounded", "Border style of the preview"},
+	{"fzf-tmux.preview_dir_cmd", []string{"ls"}, `Command previewing dirs, e.g. ["eza", "-lah", "--color", "always"]`},
+	{"fzf-tmux.preview_pos", "right", `Side of the preview: "right", "left", "up" or "down"`},
+	{"find.dirs", []string{"$HOME"}, "Dirs whose subdirs are listed as session roots"},
+	{"find.max_depth", 1, "Levels to descend below each dir, 1 lists only its children"},
+	{"find.markers", []string{".git", "go.mod", "package.json", "flake.nix"}, "A dir with any of these is a project, which isn't descended into"},
+	{"find.exclude", []string{}, "Gitignore-style patterns of dirs to skip, relative to each root"},
+	{"find.gitignore", true, "Skip dirs ignored by .gitignore and .ignore files"},
+	{"find.ignore_file", defaultIgnoreFile(), "Gitignore-style patterns applied to every root"},
+	{"find.workers", 0, "Max dirs read at once, 0 uses the number of CPUs"},
+	{"find.cache_ttl", "5m", `How long scan results are reused, "0" disables the cache`},
+	{"dmenu.cmd", []string{"rofi", "-dmenu", "-i"}, `Picker reading items on stdin, e.g. ["fuzzel", "--dmenu"]`},
+	{"naming.strategy", "basename", `How sessions are named after dirs: "basename", "parent", "git-remote" or "template"`},
+	{"naming.template", "", `Template of the "template" strategy, e.g. "{{.Owner}}/{{.Base}}"`},
+	{"naming.remote", "origin", "Git remote used by the \"git-remote\" strategy and for .Owner and .Repo"},
+	{"snapshot.keep", 5, "Number of snapshots to keep"},
+	{"snapshot.restore_cmds", []string{"nvim", "vim", "htop", "btop", "less", "man"}, "Commands rerun in panes on restore"},
+}
+
+// configFile is the config file that was loaded; empty if there was none
+var configFile string
+
+// configProfile is the profile merged into the config; empty if none was selected
+var configProfile string
+
+// envPrefix starts the env vars overriding config keys, e.g. FLOW_FIND_MAX_DEPTH for find.max_depth
+const envPrefix = "FLOW_"
+
+// loadConfig loads the defaults, then the config file, then the profile, then the FLOW_*
+// env vars, each overriding the last. path is the file given with --config or $FLOW_CONFIG
+// and profile the one given with --profile or $FLOW_PROFILE, if any
+func loadConfig(path string, profile string) error {
+	k, configFile, configProfile = koanf.New("."), "", ""
+
+	defaultValues := make(map[string]any, len(defaults))
+	for _, setting := range defaults {
+		defaultValues[setting.key] = setting.value
+	}
+	k.Load(confmap.Provider(defaultValues, "."), nil)
+
+	filename, err := configPath(path)
+	if err != nil {
+		return err
+	}
+	if err := k.Load(file.Provider(filename), toml.Parser()); err != nil {
+		// NOTE: without a config file flow runs on the defaults, unless the file was asked for
+		if path != "" || !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error loading config file %s: %w", filename, err)
+		}
+	} else {
+		configFile = filename
+	}
+
+	if profile != "" {
+		if err := loadProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	if err := loadEnv(); err != nil {
+		return fmt.Errorf("error loading config from env: %w", err)
+	}
+	return nil
+}
+
+// loadProfile merges a profile of the config file, e.g. [profile.work], into the settings
+// it overrides
+func loadProfile(profile string) error {
+	profiles := k.MapKeys("profile")
+	if !slices.Contains(profiles, profile) {
+		if len(profiles) == 0 {
+			return fmt.Errorf("unknown profile %q; the config has none", profile)
+		}
+		return fmt.Errorf("unknown profile %q; the config has %s", profile, strings.Join(profiles, ", "))
+	}
+	if err := k.Merge(k.Cut("profile." + profile)); err != nil {
+		return fmt.Errorf("error loading profile %s: %w", profile, err)
+	}
+	configProfile = profile
+	return nil
+}
+
+// profileKey returns the key of the selected profile overriding a config key, e.g.
+// "profile.work.find.dirs" for "find.dirs", and whether the profile sets it
+func profileKey(key string) (string, bool) {
+	if configProfile == "" {
+		return "", false
+	}
+	prefix := "profile." + configProfile + "."
+	base, _, _ := strings.Cut(key, "[")
+	return prefix + key, k.Exists(prefix + base)
+}
+
+// configPath returns the config file to load: the given one, or else
+// $XDG_CONFIG_HOME/flow/config.toml, falling back to ~/.config/flow/config.toml
+func configPath(path string) (string, error) {
+	if path != "" {
+		return finder.ExpandPath(path), nil
+	}
+	config, err := xdg.ConfigHome()
+	if err != nil {
+		return "", fmt.Errorf("couldn't determine config directory: %w", err)
+	}
+	return filepath.Join(config, "flow", "config.toml"), nil
+}
+
+// defaultIgnoreFile returns $XDG_CONFIG_HOME/flow/ignore, falling back to
+// ~/.config/flow/ignore, or "" for no ignore file if neither can be determined
+func defaultIgnoreFile() string {
+	config, err := xdg.ConfigHome()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(config, "flow", "ignore")
+}
+
+// loadEnv overrides the config keys that hold plain values with env vars named after
+// them: FLOW_ followed by the key in upper case, with dots and dashes as underscores,
+// e.g. FLOW_FZF_TMUX_WIDTH for fzf-tmux.width. Lists are comma separated. Other FLOW_*
+// vars, e.g. FLOW_CONFIG, are ignored, as are the keys of profiles
+func loadEnv() error {
+	keys := make(map[string]string)
+	for _, key := range k.Keys() {
+		if isPlainValue(k.Get(key)) && !strings.HasPrefix(key, "profile.") {
+			keys[envName(key)] = key
+		}
+	}
+
+	return k.Load(env.ProviderWithValue(envPrefix, ".", func(name string, value string) (string, any) {
+		key, ok := keys[name]
+		if !ok {
+			return "", nil
+		}
+		// NOTE: values that don't parse stay strings for validation to point at
+		switch k.Get(key).(type) {
+		case []string, []any:
+			if value == "" {
+				return key, []string{}
+			}
+			items := strings.Split(value, ",")
+			for i := range items {
+				items[i] = strings.TrimSpace(items[i])
+			}
+			return key, items
+		case int, int64:
+			if n, err := strconv.Atoi(value); err == nil {
+				return key, n
+			}
+		case bool:
+			if b, err := strconv.ParseBool(value); err == nil {
+				return key, b
+			}
+		}
+		return key, value
+	}), nil)
+}
+
+// envName returns the env var overriding a config key
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// isPlainValue reports whether a config value can be given as an env var: anything
+// but tables and lists of tables, e.g. [[project]]
+func isPlainValue(value any) bool {
+	switch v := value.(type) {
+	case map[string]any, []map[string]any:
+		return false
+	case []any:
+		for _, item := range v {
+			if _, ok := item.(map[string]any); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validateConfig checks the loaded config, pointing each problem at the line of the config
+// file or the env var its value came from. Problems with values of the selected profile are
+// reported under the profile's keys
+func validateConfig() error {
+	_, errs := config.Load(k)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var lines map[string]int
+	if configFile != "" {
+		// NOTE: the file already loaded, so it parses
+		lines, _ = config.Lines(configFile)
+	}
+	// NOTE: a value of the wrong type in a profile is reported for both the profile and
+	// the setting it was merged into, which become the same problem
+	var checked config.Errors
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		base, _, _ := strings.Cut(err.Key, "[")
+		if pk, ok := profileKey(err.Key); ok && !hasEnv(envName(base)) {
+			err.Key = pk
+		}
+		err.Source = configSource(err.Key, lines)
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			checked = append(checked, err)
+		}
+	}
+	return checked
+}
+
+// validateProfiles checks the loaded config like validateConfig, then loads it with each of
+// its other profiles in turn to check them too, since they'd otherwise only be checked once
+// in use. path and profile are the ones the config was loaded with, and the config is left
+// loaded with them
+func validateProfiles(path string, profile string) error {
+	// NOTE: loading a profile replaces the loaded config, so it's put back once checked
+	loaded, loadedFile, loadedProfile := k, configFile, configProfile
+	defer func() {
+		k, configFile, configProfile = loaded, loadedFile, loadedProfile
+	}()
+
+	var errs config.Errors
+	errors.As(validateConfig(), &errs)
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		seen[err.Error()] = true
+	}
+
+	for _, name := range k.MapKeys("profile") {
+		if name == profile {
+			continue
+		}
+		if err := loadConfig(path, name); err != nil {
+			return err
+		}
+		var profileErrs config.Errors
+		if !errors.As(validateConfig(), &profileErrs) {
+			continue
+		}
+		for _, err := range profileErrs {
+			if strings.HasPrefix(err.Key, "profile."+name+".") && !seen[err.Error()] {
+				seen[err.Error()] = true
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// hasEnv reports whether an env var is set, even if empty
+func hasEnv(name string) bool {
+	_, ok := os.LookupEnv(name)
+	return ok
+}
+
+// configSource returns where the value of a key came from: the env var overriding it, or
+// else its line of the config file, in the selected profile if that sets it
+func configSource(key string, lines map[string]int) string {
+	base := key
+	if i := strings.IndexByte(base, '['); i >= 0 {
+		base = base[:i]
+	}
+	if name := envName(base); hasEnv(name) {
+		return "$" + name
+	}
+	if pk, ok := profileKey(key); ok {
+		key = pk
+	}
+
+	return fileSource(configFile, key, lines)
+}
+
+// fileSource returns the line of a TOML file with the key, or the closest table or list
+// holding it; empty if neither is in the file
+func fileSource(file string, key string, lines map[string]int) string {
+	for key != "" {
+		if line, ok := lines[key]; ok {
+			return fmt.Sprintf("%s:%d", file, line)
+		}
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func Detach() *cli.Command {
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "detach",
+		Usage:                  "Detach every client from a session, or the client flow runs in if no session is given",
+		ArgsUsage:              "[session]",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			server := targetServer(cmd, socketName, socketPath)
+
+			switch cmd.NArg() {
+			case 0:
+				current, err := currentOn(ctx, server)
+				if err != nil {
+					return exitError("error while finding current client", err)
+				}
+				if current == nil || current.Client == "" {
+					return cli.Exit("expected the session to detach since flow isn't in a client of the server", 1)
+				}
+				if err := server.DetachClient(ctx, current.Client); err != nil {
+					return exitError("error while detaching client", err)
+				}
+			case 1:
+				if err := server.DetachSession(ctx, cmd.Args().First()); err != nil {
+					return exitError("error while detaching session", err)
+				}
+			default:
+				return cli.Exit("expected at most one session", 1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// Exit codes for the errors flow knows how to explain
+const (
+	exitNoServer        = 3
+	exitSessionNotFound = 4
+	exitSessionExists   = 5
+	exitNested          = 6
+	exitTimeout         = 124 // NOTE: same as timeout(1)
+	exitTmuxNotFound    = 127 // NOTE: same as the shell's command not found
+)
+
+// exitError turns an error, usually from tmux, into an exit error with a hint on what
+// to do about it and an exit code scripts can check. msg describes what flow was doing
+func exitError(msg string, err error) cli.ExitCoder {
+	code, hint := 1, ""
+	switch {
+	case errors.Is(err, context.Canceled):
+		// NOTE: the user pressed <ctrl-c>, so there's nothing to explain
+		return cli.Exit("", exitCancelled)
+	case errors.Is(err, context.DeadlineExceeded):
+		code, hint = exitTimeout, "tmux didn't respond in time; the server may be stuck, or raise flow.timeout"
+	case errors.Is(err, tmux.ErrTmuxNotFound):
+		code, hint = exitTmuxNotFound, "install tmux or add it to the PATH"
+	case errors.Is(err, tmux.ErrNotInTmux):
+		hint = "run it from a tmux pane or key binding"
+	case errors.Is(err, tmux.ErrNested):
+		code, hint = exitNested, "already inside tmux; use `flow switch` or unset $TMUX to force it"
+	case errors.Is(err, tmux.ErrNoServer):
+		code, hint = exitNoServer, "start one with `flow start`, or pick another with --name or --path"
+	case errors.Is(err, tmux.ErrServerExists):
+		code, hint = exitSessionExists, "attach to it with `flow attach`"
+	case errors.Is(err, tmux.ErrSessionNotFound):
+		code, hint = exitSessionNotFound, "see the sessions with `flow list`"
+	case errors.Is(err, tmux.ErrDuplicateSession):
+		code = exitSessionExists
+	}
+
+	if hint != "" {
+		return cli.Exit(fmt.Sprintf("%s: %v\nhint: %s", msg, err, hint), code)
+	}
+	return cli.Exit(fmt.Sprintf("%s: %v", msg, err), code)
+}
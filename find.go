@@ -3,63 +3,138 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
-	"os/user"
 	"path/filepath"
 	"slices"
 	"strings"
 
 	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/finder"
+	"github.com/winter-again/flow/internal/history"
+	"github.com/winter-again/flow/internal/xdg"
 )
 
 func Find() *cli.Command {
+	var refresh bool
+
 	return &cli.Command{
 		Name:  "find",
 		Usage: "List candidate directories for roots of new tmux sessions",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "refresh",
+				Aliases:     []string{"r"},
+				Usage:       "Rescan every root instead of using cached results",
+				Destination: &refresh,
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			findDirs := k.Strings("find.dirs")
-
-			// TODO: should there be more validation of find.dirs data?
-			// e.g., ignore duplicates, handle empty slice?
-
-			var childDirs []string
-			for _, parent := range findDirs {
-				if strings.HasPrefix(parent, "~/") {
-					user, err := user.Current()
-					if err != nil {
-						log.Fatal(err)
-					}
-					parent = filepath.Join(user.HomeDir, parent[2:])
-				}
-
-				file, err := os.Open(parent)
-				if err != nil {
-					log.Fatal(err)
-				}
-				defer file.Close()
-
-				dirs, err := file.Readdirnames(0)
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				path, err := filepath.Abs(parent)
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				for _, dir := range dirs {
-					childDirs = append(childDirs, filepath.Join(path, dir))
-				}
+			childDirs, err := findDirs(ctx, refresh)
+			if err != nil {
+				return exitError("error while finding dirs", err)
 			}
 
-			// TODO: make optional?
-			slices.Sort(childDirs)
-
 			out := strings.Join(childDirs, "\n")
 			fmt.Println(out)
 			return nil
 		},
 	}
 }
+
+// findDirs lists the candidate dirs under every find.dirs and find.roots entry. Roots
+// that can't be searched are reported as warnings so that the others are still listed
+func findDirs(ctx context.Context, refresh bool) ([]string, error) {
+	roots, err := findRoots()
+	if err != nil {
+		return []string{}, err
+	}
+	ignore, err := finder.ReadIgnoreFile(finder.ExpandPath(k.String("find.ignore_file")))
+	if err != nil {
+		warn(fmt.Errorf("couldn't read ignore file: %w", err))
+	}
+	opts := finder.Options{
+		Markers:   k.Strings("find.markers"),
+		Exclude:   k.Strings("find.exclude"),
+		Ignore:    ignore,
+		GitIgnore: k.Bool("find.gitignore"),
+		Workers:   k.Int("find.workers"),
+	}
+	cache := findCache()
+
+	var dirs []string
+	var scan []finder.Root
+	for _, root := range roots {
+		if cache != nil && !refresh {
+			if cached, ok := cache.Get(root, opts); ok {
+				dirs = append(dirs, cached...)
+				continue
+			}
+		}
+		scan = append(scan, root)
+	}
+
+	results := finder.FindAll(ctx, scan, opts)
+	if err := ctx.Err(); err != nil {
+		return []string{}, err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			warn(fmt.Errorf("skipping %s: %w", result.Root.Path, result.Err))
+			continue
+		}
+		dirs = append(dirs, result.Dirs...)
+
+		if cache != nil {
+			if err := cache.Put(result, opts); err != nil {
+				warn(err)
+			}
+		}
+	}
+
+	// NOTE: overlapping roots would otherwise list the same dir twice
+	slices.Sort(dirs)
+	dirs = slices.Compact(dirs)
+
+	sortByHistory(history.Dirs, dirs)
+	return dirs, nil
+}
+
+// findCache returns the cache for find results, or nil if caching is off
+func findCache() *finder.Cache {
+	ttl := k.Duration("find.cache_ttl")
+	if ttl <= 0 {
+		return nil
+	}
+
+	cacheHome, err := xdg.CacheHome()
+	if err != nil {
+		warn(fmt.Errorf("couldn't determine cache directory: %w", err))
+		return nil
+	}
+	return &finder.Cache{
+		Dir: filepath.Join(cacheHome, "flow", "find"),
+		TTL: ttl,
+	}
+}
+
+// findRoots combines the plain find.dirs, which use the find table's max_depth,
+// with the find.roots tables that may set their own
+func findRoots() ([]finder.Root, error) {
+	maxDepth := k.Int("find.max_depth")
+
+	var roots []finder.Root
+	for _, dir := range k.Strings("find.dirs") {
+		roots = append(roots, finder.Root{Path: dir, MaxDepth: maxDepth})
+	}
+
+	var custom []finder.Root
+	if err := k.Unmarshal("find.roots", &custom); err != nil {
+		return []finder.Root{}, fmt.Errorf("error reading find.roots from config: %w", err)
+	}
+	for _, root := range custom {
+		if root.MaxDepth == 0 {
+			root.MaxDepth = maxDepth
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
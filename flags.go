@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/finder"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// socketFlags returns the mutually exclusive --name and --path flags used to
+// select the tmux server a command talks to
+func socketFlags(socketName *string, socketPath *string) []cli.MutuallyExclusiveFlags {
+	return []cli.MutuallyExclusiveFlags{
+		{
+			Flags: [][]cli.Flag{
+				{
+					&cli.StringFlag{
+						Name:        "name",
+						Aliases:     []string{"n"},
+						Value:       *socketName,
+						Usage:       "tmux server socket name",
+						Destination: socketName,
+					},
+				},
+				{
+					&cli.StringFlag{
+						Name:        "path",
+						Aliases:     []string{"p"},
+						Value:       *socketPath,
+						Usage:       "tmux server socket path",
+						Destination: socketPath,
+					},
+				},
+			},
+		},
+	}
+}
+
+// targetServer returns the server chosen with --name or --path; otherwise the one flow
+// runs in when it's inside tmux, or else the one of flow.socket
+func targetServer(cmd *cli.Command, socketName string, socketPath string) *tmux.Server {
+	if !cmd.IsSet("name") && !cmd.IsSet("path") && tmux.InsideTmux() {
+		if server, err := tmux.GetCurrentServer(); err == nil {
+			return server
+		}
+	}
+	return flagServer(cmd, socketName, socketPath)
+}
+
+// flagServer returns the server chosen with --name or --path; otherwise the one of
+// flow.socket, which is tmux's default server unless set
+func flagServer(cmd *cli.Command, socketName string, socketPath string) *tmux.Server {
+	socket := k.String("flow.socket")
+	if cmd.IsSet("name") || cmd.IsSet("path") || socket == "" {
+		return tmux.NewServer(socketName, socketPath)
+	}
+
+	// NOTE: like tmux's -L and -S, a socket with a slash is a path and anything else a name
+	defaultName, defaultPath := tmux.GetDefaultSocket()
+	if strings.ContainsRune(socket, filepath.Separator) {
+		return tmux.NewServer(defaultName, finder.ExpandPath(socket))
+	}
+	return tmux.NewServer(socket, defaultPath)
+}
+
+// currentOn returns the client, session and pane flow runs in if that's on the server;
+// nil if flow isn't inside tmux or is inside another server
+func currentOn(ctx context.Context, server *tmux.Server) (*tmux.Current, error) {
+	env, err := tmux.GetEnv()
+	if err != nil || env.SocketPath != server.SocketPath {
+		return nil, nil
+	}
+	return server.GetCurrent(ctx)
+}
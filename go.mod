@@ -5,9 +5,12 @@ go 1.24.4
 require (
 	github.com/knadh/koanf/parsers/toml v0.1.0
 	github.com/knadh/koanf/providers/confmap v1.0.0
+	github.com/knadh/koanf/providers/env v1.1.0
 	github.com/knadh/koanf/providers/file v1.2.0
 	github.com/knadh/koanf/v2 v2.2.1
+	github.com/pelletier/go-toml v1.9.5
 	github.com/urfave/cli/v3 v3.3.8
+	golang.org/x/term v0.31.0
 )
 
 require (
@@ -16,6 +19,5 @@ require (
 	github.com/knadh/koanf/maps v0.1.2 // indirect
 	github.com/mitchellh/copystructure v1.2.0 // indirect
 	github.com/mitchellh/reflectwalk v1.0.2 // indirect
-	github.com/pelletier/go-toml v1.9.5 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 )
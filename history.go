@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/winter-again/flow/internal/history"
+	"github.com/winter-again/flow/internal/tmux"
+	"github.com/winter-again/flow/internal/xdg"
+)
+
+// loadHistory loads the record of sessions and dirs flow has switched to
+func loadHistory() (*history.Store, error) {
+	state, err := xdg.StateHome()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't determine state directory: %w", err)
+	}
+	return history.Load(filepath.Join(state, "flow", "history.json"))
+}
+
+// sortByHistory orders items using the flow.sort method. Problems with the history
+// only cost the ordering, so they're reported as warnings
+func sortByHistory(kind history.Kind, items []string) {
+	store, err := loadHistory()
+	if err != nil {
+		warn(err)
+	}
+	if store == nil {
+		return
+	}
+	if err := store.Sort(kind, items, k.String("flow.sort"), time.Now()); err != nil {
+		warn(err)
+	}
+}
+
+// recordVisit adds a switch to a session, and the dir it was created from if any, to the
+// history. The session switched from, if known, goes on the stack first so that `flow last`
+// can go back to it even if flow didn't switch to it
+func recordVisit(from *history.Visit, to history.Visit, createdFrom string) {
+	store, err := loadHistory()
+	if err != nil {
+		warn(err)
+		return
+	}
+
+	now := time.Now()
+	if from != nil {
+		from.Time = now
+		store.Push(*from)
+	}
+	to.Time = now
+	if to.Path == "" {
+		to.Path = createdFrom
+	}
+	store.Push(to)
+	store.Add(history.Sessions, to.Session, now)
+	if createdFrom != "" {
+		store.Add(history.Dirs, createdFrom, now)
+	}
+	if err := store.Save(); err != nil {
+		warn(err)
+	}
+}
+
+// visitTo returns the history entry for a switch to a session of the server
+func visitTo(server *tmux.Server, session *tmux.Session) history.Visit {
+	return history.Visit{
+		Session: session.Name,
+		Path:    session.Path,
+		Server:  server.SocketPath,
+	}
+}
+
+// currentVisit returns the history entry for the session flow runs in, found among the
+// sessions of the server, or nil if flow doesn't run in one of them
+func currentVisit(server *tmux.Server, current *tmux.Current, sessions []*tmux.Session) *history.Visit {
+	if current == nil {
+		return nil
+	}
+	for _, session := range sessions {
+		if session.Id == current.SessionId {
+			visit := visitTo(server, session)
+			return &visit
+		}
+	}
+	return nil
+}
+
+// sessionVisit returns the history entry for the session flow runs in, on whichever server,
+// or nil outside tmux. Problems only cost the entry, so they're reported as warnings
+func sessionVisit(ctx context.Context) *history.Visit {
+	server, err := tmux.GetCurrentServer()
+	if err != nil {
+		return nil
+	}
+	current, err := server.GetCurrent(ctx)
+	if err != nil {
+		warn(err)
+		return nil
+	}
+	sessions, err := server.GetSessions(ctx)
+	if err != nil {
+		warn(err)
+		return nil
+	}
+	return currentVisit(server, current, sessions)
+}
+
+// warn reports a problem that doesn't stop the command
+func warn(err error) {
+	fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+}
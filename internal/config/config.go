@@ -0,0 +1,523 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/v2"
+	"github.com/pelletier/go-toml"
+
+	"github.com/winter-again/flow/internal/finder"
+	"github.com/winter-again/flow/internal/history"
+	"github.com/winter-again/flow/internal/layout"
+	"github.com/winter-again/flow/internal/naming"
+)
+
+// Config is every setting flow reads, as found in config.toml
+type Config struct {
+	Flow     Flow               `koanf:"flow"`
+	FzfTmux  FzfTmux            `koanf:"fzf-tmux"`
+	Find     Find               `koanf:"find"`
+	Dmenu    Dmenu              `koanf:"dmenu"`
+	Naming   Naming             `koanf:"naming"`
+	Snapshot Snapshot           `koanf:"snapshot"`
+	Projects []layout.Project   `koanf:"project"`
+	Profiles map[string]Profile `koanf:"profile"`
+}
+
+type Flow struct {
+	InitSessionName string        `koanf:"init_session_name"`
+	Picker          string        `koanf:"picker"`
+	Sort            string        `koanf:"sort"`
+	Timeout         time.Duration `koanf:"timeout"`
+	Trust           []string      `koanf:"trust"`
+	Socket          string        `koanf:"socket"`
+}
+
+type FzfTmux struct {
+	Width         string   `koanf:"width"`
+	Length        string   `koanf:"length"`
+	Border        string   `koanf:"border"`
+	PreviewSize   string   `koanf:"preview_size"`
+	PreviewBorder string   `koanf:"preview_border"`
+	PreviewDirCmd []string `koanf:"preview_dir_cmd"`
+	PreviewPos    string   `koanf:"preview_pos"`
+}
+
+type Find struct {
+	Dirs       []string      `koanf:"dirs"`
+	MaxDepth   int           `koanf:"max_depth"`
+	Markers    []string      `koanf:"markers"`
+	Exclude    []string      `koanf:"exclude"`
+	GitIgnore  bool          `koanf:"gitignore"`
+	IgnoreFile string        `koanf:"ignore_file"`
+	Workers    int           `koanf:"workers"`
+	CacheTTL   time.Duration `koanf:"cache_ttl"`
+	Roots      []finder.Root `koanf:"roots"`
+}
+
+type Dmenu struct {
+	Cmd []string `koanf:"cmd"`
+}
+
+type Naming struct {
+	Strategy string `koanf:"strategy"`
+	Template string `koanf:"template"`
+	Remote   string `koanf:"remote"`
+}
+
+// Profile overrides settings of the config when it's selected with --profile or
+// $FLOW_PROFILE, e.g. to give each context its own tmux server and dirs
+type Profile struct {
+	Flow    ProfileFlow `koanf:"flow"`
+	FzfTmux FzfTmux     `koanf:"fzf-tmux"`
+	Find    Find        `koanf:"find"`
+}
+
+// ProfileFlow is the part of [flow] a profile can override
+type ProfileFlow struct {
+	InitSessionName string `koanf:"init_session_name"`
+	Socket          string `koanf:"socket"`
+}
+
+type Snapshot struct {
+	Keep        int      `koanf:"keep"`
+	RestoreCmds []string `koanf:"restore_cmds"`
+}
+
+// Allowed values of the enum settings
+var (
+	Pickers        = []string{"fzf-tmux", "fzf", "skim", "dmenu", "builtin"}
+	Sorts          = []string{history.SortAlpha, history.SortRecency, history.SortFrecency}
+	Borders        = []string{"rounded", "sharp", "bold", "double", "block", "thinblock", "horizontal", "vertical", "top", "bottom", "left", "right", "none"}
+	PreviewPosList = []string{"right", "left", "up", "down"}
+	Splits         = []string{"", "horizontal", "vertical"}
+)
+
+// Error is a problem with the value of one key
+type Error struct {
+	Key    string // e.g. "find.roots[1].max_depth"
+	Msg    string
+	Source string // where the value came from, e.g. "config.toml:12"; empty if unknown
+}
+
+func (e *Error) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Source, e.Key, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Msg)
+}
+
+// Errors is every problem found in a config, in key order
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Load decodes and validates the config held by k. Unknown keys and values of the wrong
+// type are reported along with invalid values; the config is only returned without errors
+func Load(k *koanf.Koanf) (*Config, Errors) {
+	var errs Errors
+	// NOTE: what passes the type checks is still validated, so that every problem is
+	// reported at once
+	checked, _ := checkTypes(&errs, "", k.Raw(), reflect.TypeOf(Config{}))
+
+	var cfg Config
+	if err := decode(checked, &cfg); err != nil {
+		return nil, Errors{{Key: "config", Msg: err.Error()}}
+	}
+	errs = append(errs, withoutTypeErrors(cfg.Validate(), errs)...)
+	if len(errs) > 0 {
+		sortErrors(errs)
+		return nil, errs
+	}
+	return &cfg, nil
+}
+
+// Validate checks the values of the settings
+func (cfg *Config) Validate() Errors {
+	var errs Errors
+	add := func(key string, format string, args ...any) {
+		errs = append(errs, &Error{Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(key string, value string, allowed []string) {
+		if !slices.Contains(allowed, value) {
+			add(key, "expected one of %s but got %q", quoteAll(allowed), value)
+		}
+	}
+	size := func(key string, value string) {
+		if err := checkSize(value); err != nil {
+			add(key, "%v", err)
+		}
+	}
+	path := func(key string, value string) {
+		if err := checkPath(value); err != nil {
+			add(key, "%v", err)
+		}
+	}
+	cmd := func(key string, value []string) {
+		if len(value) == 0 || value[0] == "" {
+			add(key, "expected a command but got an empty list")
+		}
+	}
+
+	if cfg.Flow.InitSessionName == "" {
+		add("flow.init_session_name", "expected a session name but got nothing")
+	}
+	oneOf("flow.picker", cfg.Flow.Picker, Pickers)
+	oneOf("flow.sort", cfg.Flow.Sort, Sorts)
+	if cfg.Flow.Timeout < 0 {
+		add("flow.timeout", "expected a duration of 0 or more but got %s", cfg.Flow.Timeout)
+	}
+	for i, pattern := range cfg.Flow.Trust {
+		path(fmt.Sprintf("flow.trust[%d]", i), pattern)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			add(fmt.Sprintf("flow.trust[%d]", i), "invalid glob %q: %v", pattern, err)
+		}
+	}
+	if strings.ContainsRune(cfg.Flow.Socket, filepath.Separator) {
+		path("flow.socket", cfg.Flow.Socket)
+	}
+
+	size("fzf-tmux.width", cfg.FzfTmux.Width)
+	size("fzf-tmux.length", cfg.FzfTmux.Length)
+	oneOf("fzf-tmux.border", cfg.FzfTmux.Border, Borders)
+	if err := checkPercent(cfg.FzfTmux.PreviewSize); err != nil {
+		add("fzf-tmux.preview_size", "%v", err)
+	}
+	oneOf("fzf-tmux.preview_border", cfg.FzfTmux.PreviewBorder, Borders)
+	cmd("fzf-tmux.preview_dir_cmd", cfg.FzfTmux.PreviewDirCmd)
+	oneOf("fzf-tmux.preview_pos", cfg.FzfTmux.PreviewPos, PreviewPosList)
+
+	for i, dir := range cfg.Find.Dirs {
+		path(fmt.Sprintf("find.dirs[%d]", i), dir)
+	}
+	if cfg.Find.MaxDepth < 1 {
+		add("find.max_depth", "expected 1 or more but got %d", cfg.Find.MaxDepth)
+	}
+	for i, marker := range cfg.Find.Markers {
+		if marker == "" || strings.ContainsRune(marker, filepath.Separator) {
+			add(fmt.Sprintf("find.markers[%d]", i), "expected a file or dir name but got %q", marker)
+		}
+	}
+	if cfg.Find.IgnoreFile != "" {
+		path("find.ignore_file", cfg.Find.IgnoreFile)
+	}
+	if cfg.Find.Workers < 0 {
+		add("find.workers", "expected 0 or more but got %d", cfg.Find.Workers)
+	}
+	if cfg.Find.CacheTTL < 0 {
+		add("find.cache_ttl", "expected a duration of 0 or more but got %s", cfg.Find.CacheTTL)
+	}
+	for i, root := range cfg.Find.Roots {
+		path(fmt.Sprintf("find.roots[%d].path", i), root.Path)
+		if root.MaxDepth < 0 {
+			add(fmt.Sprintf("find.roots[%d].max_depth", i), "expected 0 or more but got %d", root.MaxDepth)
+		}
+	}
+
+	cmd("dmenu.cmd", cfg.Dmenu.Cmd)
+
+	if err := naming.Validate(naming.Options{Strategy: cfg.Naming.Strategy, Template: cfg.Naming.Template}); err != nil {
+		key := "naming.strategy"
+		if cfg.Naming.Strategy == naming.Template {
+			key = "naming.template"
+		}
+		add(key, "%v", err)
+	}
+
+	for i, project := range cfg.Projects {
+		key := fmt.Sprintf("project[%d]", i)
+		if len(project.Match) == 0 {
+			add(key+".match", "expected dirs or globs but got nothing")
+		}
+		for j, pattern := range project.Match {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				add(fmt.Sprintf("%s.match[%d]", key, j), "invalid glob %q: %v", pattern, err)
+			}
+		}
+		errs = append(errs, validateProject(key+".", &project)...)
+	}
+	return errs
+}
+
+// LoadProject decodes and validates a project file, e.g. .flow.toml, held by k. Like Load,
+// the project is only returned without errors
+func LoadProject(k *koanf.Koanf) (*layout.Project, Errors) {
+	var errs Errors
+	checked, _ := checkTypes(&errs, "", k.Raw(), reflect.TypeOf(layout.Project{}))
+	if k.Exists("match") {
+		errs = append(errs, &Error{Key: "match", Msg: "only applies to [[project]] in the config file; a project file applies to its own dir"})
+	}
+
+	var project layout.Project
+	if err := decode(checked, &project); err != nil {
+		return nil, Errors{{Key: "project", Msg: err.Error()}}
+	}
+	errs = append(errs, withoutTypeErrors(validateProject("", &project), errs)...)
+	if len(errs) > 0 {
+		sortErrors(errs)
+		return nil, errs
+	}
+	return &project, nil
+}
+
+// validateProject checks the settings of a project besides its patterns; prefix starts
+// the keys of the errors
+func validateProject(prefix string, project *layout.Project) Errors {
+	var errs Errors
+	for name := range project.Env {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			errs = append(errs, &Error{Key: prefix + "env." + name, Msg: "expected an env var name without spaces or ="})
+		}
+	}
+	for j, window := range project.Windows {
+		for l, pane := range window.Panes {
+			if !slices.Contains(Splits, pane.Split) {
+				errs = append(errs, &Error{
+					Key: fmt.Sprintf("%swindow[%d].pane[%d].split", prefix, j, l),
+					Msg: fmt.Sprintf("expected one of %s but got %q", quoteAll(Splits), pane.Split),
+				})
+			}
+		}
+	}
+	return errs
+}
+
+// checkSize checks a popup dimension: a number of cells or a percentage, e.g. "80%"
+func checkSize(value string) error {
+	if strings.HasSuffix(value, "%") {
+		return checkPercent(value)
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 {
+		return fmt.Errorf("expected a number of cells or a percentage, e.g. \"80%%\", but got %q", value)
+	}
+	return nil
+}
+
+// checkPercent checks a percentage from 1% to 100%; the % is optional
+func checkPercent(value string) error {
+	n, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || n < 1 || n > 100 {
+		return fmt.Errorf("expected a percentage from 1%% to 100%% but got %q", value)
+	}
+	return nil
+}
+
+// checkPath checks that a path is absolute once ~ and env vars are expanded. Whether it
+// exists isn't checked, so that configs shared between machines can be validated anywhere
+func checkPath(value string) error {
+	if value == "" {
+		return fmt.Errorf("expected a path but got nothing")
+	}
+	if !filepath.IsAbs(finder.ExpandPath(value)) {
+		return fmt.Errorf("expected an absolute path, or one starting with ~ or $HOME, but got %q", value)
+	}
+	return nil
+}
+
+// quoteAll formats allowed values for error messages
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			quoted = append(quoted, strconv.Quote(value))
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// checkTypes compares a value of the koanf tree with the type it's decoded into,
+// reporting unknown keys and values of the wrong type. Keys of nested tables are
+// joined with dots and items of lists are indexed, e.g. "project[0].window[1].name".
+// Returns the value without what was reported, and false if the value itself was; a
+// list with an item of the wrong type is left out whole, so the indexes of the rest
+// don't shift
+func checkTypes(errs *Errors, key string, value any, t reflect.Type) (any, bool) {
+	wrong := func(expected string) (any, bool) {
+		*errs = append(*errs, &Error{Key: key, Msg: fmt.Sprintf("expected %s but got %s", expected, describe(value))})
+		return nil, false
+	}
+
+	switch {
+	case t == durationType:
+		s, ok := value.(string)
+		if !ok {
+			return wrong("a duration, e.g. \"5s\"")
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			*errs = append(*errs, &Error{Key: key, Msg: fmt.Sprintf("expected a duration, e.g. \"5s\", but got %q", s)})
+			return nil, false
+		}
+	case t.Kind() == reflect.Struct:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return wrong("a table")
+		}
+		fields := make(map[string]reflect.Type)
+		for i := range t.NumField() {
+			field := t.Field(i)
+			fields[field.Tag.Get("koanf")] = field.Type
+		}
+		checked := make(map[string]any, len(table))
+		for name, v := range table {
+			sub := name
+			if key != "" {
+				sub = key + "." + name
+			}
+			ft, ok := fields[name]
+			if !ok {
+				*errs = append(*errs, &Error{Key: sub, Msg: "unknown key"})
+				continue
+			}
+			if v, ok := checkTypes(errs, sub, v, ft); ok {
+				checked[name] = v
+			}
+		}
+		return checked, true
+	case t.Kind() == reflect.Map:
+		table, ok := value.(map[string]any)
+		if !ok {
+			return wrong("a table")
+		}
+		checked := make(map[string]any, len(table))
+		for name, v := range table {
+			if v, ok := checkTypes(errs, key+"."+name, v, t.Elem()); ok {
+				checked[name] = v
+			}
+		}
+		return checked, true
+	case t.Kind() == reflect.Slice:
+		list := reflect.ValueOf(value)
+		if value == nil || list.Kind() != reflect.Slice {
+			return wrong("a list")
+		}
+		checked := make([]any, list.Len())
+		valid := true
+		for i := range list.Len() {
+			item, ok := checkTypes(errs, fmt.Sprintf("%s[%d]", key, i), list.Index(i).Interface(), t.Elem())
+			checked[i], valid = item, valid && ok
+		}
+		return checked, valid
+	case t.Kind() == reflect.String:
+		if _, ok := value.(string); !ok {
+			return wrong("a string")
+		}
+	case t.Kind() == reflect.Int:
+		switch value.(type) {
+		case int, int64:
+		default:
+			return wrong("an integer")
+		}
+	case t.Kind() == reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return wrong("true or false")
+		}
+	}
+	return value, true
+}
+
+// decode decodes a tree returned by checkTypes into out
+func decode(tree any, out any) error {
+	table, _ := tree.(map[string]any)
+	checked := koanf.New(".")
+	if err := checked.Load(confmap.Provider(table, ""), nil); err != nil {
+		return err
+	}
+	return checked.UnmarshalWithConf("", out, koanf.UnmarshalConf{Tag: "koanf"})
+}
+
+// withoutTypeErrors returns the value errors that aren't about a key with a type error,
+// or one inside or around it, since those keys were left out of what was validated
+func withoutTypeErrors(errs Errors, typeErrs Errors) Errors {
+	var kept Errors
+	for _, err := range errs {
+		related := slices.ContainsFunc(typeErrs, func(typeErr *Error) bool {
+			return within(err.Key, typeErr.Key) || within(typeErr.Key, err.Key)
+		})
+		if !related {
+			kept = append(kept, err)
+		}
+	}
+	return kept
+}
+
+// within reports whether key is parent or inside it, e.g. "find.roots[0].path" is
+// within "find.roots"
+func within(key string, parent string) bool {
+	return key == parent || strings.HasPrefix(key, parent+".") || strings.HasPrefix(key, parent+"[")
+}
+
+// describe names the type of a value of the koanf tree for error messages
+func describe(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("a string (%q)", v)
+	case int, int64, float64:
+		return fmt.Sprintf("a number (%v)", v)
+	case bool:
+		return fmt.Sprintf("%v", v)
+	case map[string]any:
+		return "a table"
+	case nil:
+		return "nothing"
+	}
+	if reflect.ValueOf(value).Kind() == reflect.Slice {
+		return "a list"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// sortErrors orders errors by key, keeping the order of errors about the same key
+func sortErrors(errs Errors) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+}
+
+// Lines maps the keys of a TOML file, in the format of Error.Key, to the lines they're on
+func Lines(path string) (map[string]int, error) {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := make(map[string]int)
+	addLines(lines, "", tree)
+	return lines, nil
+}
+
+// addLines adds the lines of the keys of a TOML table, prefixed with the table's key
+func addLines(lines map[string]int, prefix string, tree *toml.Tree) {
+	for _, name := range tree.Keys() {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if pos := tree.GetPositionPath([]string{name}); !pos.Invalid() {
+			lines[key] = pos.Line
+		}
+
+		switch v := tree.GetPath([]string{name}).(type) {
+		case *toml.Tree:
+			addLines(lines, key, v)
+		case []*toml.Tree:
+			for i, sub := range v {
+				item := fmt.Sprintf("%s[%d]", key, i)
+				lines[item] = sub.Position().Line
+				addLines(lines, item, sub)
+			}
+		}
+	}
+}
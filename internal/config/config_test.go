@@ -0,0 +1,333 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+var defaults = map[string]any{
+	"flow.init_session_name":   "0",
+	"flow.picker":              "fzf-tmux",
+	"flow.sort":                "frecency",
+	"flow.timeout":             "5s",
+	"fzf-tmux.length":          "60%",
+	"fzf-tmux.width":           "80%",
+	"fzf-tmux.border":          "rounded",
+	"fzf-tmux.preview_size":    "60%",
+	"fzf-tmux.preview_border":  "rounded",
+	"fzf-tmux.preview_dir_cmd": []string{"ls"},
+	"fzf-tmux.preview_pos":     "right",
+	"find.dirs":                []string{"$HOME"},
+	"find.max_depth":           1,
+	"find.markers":             []string{".git"},
+	"find.exclude":             []string{},
+	"find.gitignore":           true,
+	"find.ignore_file":         "~/.config/flow/ignore",
+	"find.workers":             0,
+	"find.cache_ttl":           "5m",
+	"dmenu.cmd":                []string{"rofi", "-dmenu", "-i"},
+	"naming.strategy":          "basename",
+	"naming.template":          "",
+	"naming.remote":            "origin",
+	"snapshot.keep":            5,
+	"snapshot.restore_cmds":    []string{"nvim"},
+}
+
+// load returns the defaults overridden by a TOML config, written to a temp file
+func load(t *testing.T, config string) (*koanf.Koanf, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	k := koanf.New(".")
+	if err := k.Load(confmap.Provider(defaults, "."), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Load(file.Provider(path), toml.Parser()); err != nil {
+		t.Fatal(err)
+	}
+	return k, path
+}
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		want   []string // keys of the errors, in order
+	}{
+		{name: "defaults"},
+		{
+			name: "valid",
+			config: `
+[flow]
+picker = "builtin"
+timeout = "0"
+
+[fzf-tmux]
+width = "120"
+preview_size = "40"
+
+[find]
+dirs = ["~/code", "/srv"]
+max_depth = 2
+
+[[find.roots]]
+path = "$HOME/work"
+
+[naming]
+strategy = "template"
+template = "{{.Parent}}-{{.Base}}"
+
+[[project]]
+match = ["~/code/*"]
+[[project.window]]
+[[project.window.pane]]
+split = "horizontal"
+
+[profile.work.flow]
+init_session_name = "work"
+socket = "work"
+
+[profile.work.find]
+dirs = ["~/work"]
+
+[profile.oncall.fzf-tmux]
+border = "double"
+`,
+		},
+		{
+			name: "types",
+			config: `
+[flow]
+timeout = 5
+sorting = "alpha"
+
+[find]
+dirs = "~/code"
+gitignore = "yes"
+
+[[find.roots]]
+max_depth = "2"
+
+[profile.work.flow]
+picker = "builtin"
+
+[profile.work.find]
+dirs = "~/work"
+`,
+			want: []string{"find.dirs", "find.gitignore", "find.roots[0].max_depth", "find.roots[0].path", "flow.sorting", "flow.timeout", "profile.work.find.dirs", "profile.work.flow.picker"},
+		},
+		{
+			name: "types and values",
+			config: `
+[fzf-tmux]
+preview_pos = "rigth"
+preview_dir_cmd = "ls"
+
+[find]
+typo_key = 1
+`,
+			want: []string{"find.typo_key", "fzf-tmux.preview_dir_cmd", "fzf-tmux.preview_pos"},
+		},
+		{
+			name:   "tables",
+			config: "find = 1\nnope = {}\n",
+			want:   []string{"find", "nope"},
+		},
+		{
+			name: "values",
+			config: `
+[flow]
+picker = "fzy"
+timeout = "-1s"
+socket = "tmp/work"
+
+[fzf-tmux]
+preview_pos = "rigth"
+width = "0"
+length = "101%"
+preview_size = "big"
+preview_dir_cmd = []
+
+[find]
+dirs = ["code"]
+max_depth = 0
+markers = ["a/b"]
+
+[naming]
+strategy = "template"
+
+[[project]]
+[[project.window]]
+[[project.window.pane]]
+split = "sideways"
+`,
+			want: []string{
+				"find.dirs[0]",
+				"find.markers[0]",
+				"find.max_depth",
+				"flow.picker",
+				"flow.socket",
+				"flow.timeout",
+				"fzf-tmux.length",
+				"fzf-tmux.preview_dir_cmd",
+				"fzf-tmux.preview_pos",
+				"fzf-tmux.preview_size",
+				"fzf-tmux.width",
+				"naming.template",
+				"project[0].match",
+				"project[0].window[0].pane[0].split",
+			},
+		},
+	}
+	for _, c := range cases {
+		k, _ := load(t, c.config)
+		cfg, errs := Load(k)
+		if len(errs) != len(c.want) {
+			t.Errorf("%s: expected %d errors but got %d:\n%v", c.name, len(c.want), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Key != c.want[i] {
+				t.Errorf("%s: expected error %d about %s but got %v", c.name, i, c.want[i], err)
+			}
+		}
+		if len(c.want) == 0 && cfg == nil {
+			t.Errorf("%s: expected a config but got nil", c.name)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	_, path := load(t, `[flow]
+picker = "fzf"
+
+[find]
+dirs = ["~/code"]
+
+[[find.roots]]
+path = "~/a"
+
+[[find.roots]]
+path = "~/b"
+`)
+
+	lines, err := Lines(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]int{
+		"flow.picker":        2,
+		"find.dirs":          5,
+		"find.roots[0].path": 8,
+		"find.roots[1]":      10,
+		"find.roots[1].path": 11,
+	}
+	for key, line := range want {
+		if lines[key] != line {
+			t.Errorf("%s: expected line %d but got %d", key, line, lines[key])
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	err := &Error{Key: "find.dirs", Msg: "expected a list but got a string", Source: "config.toml:3"}
+	if got, want := err.Error(), "config.toml:3: find.dirs: expected a list but got a string"; got != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+	err.Source = ""
+	if got, want := err.Error(), "find.dirs: expected a list but got a string"; got != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		want   []string // keys of the errors, in order
+	}{
+		{
+			name: "valid",
+			config: `
+name = "api"
+commands = ["make dev"]
+
+[env]
+GOFLAGS = "-race"
+
+[hooks]
+create = ["docker compose up -d"]
+
+[[window]]
+[[window.pane]]
+split = "horizontal"
+`,
+		},
+		{
+			name:   "types",
+			config: "name = 1\nenv = \"A=1\"\ncommands = \"make\"\n",
+			want:   []string{"commands", "env", "name"},
+		},
+		{
+			name: "types and values",
+			config: `
+commands = "make"
+
+[[window]]
+[[window.pane]]
+split = "sideways"
+`,
+			want: []string{"commands", "window[0].pane[0].split"},
+		},
+		{
+			name:   "match",
+			config: "match = [\"~/code/*\"]\n",
+			want:   []string{"match"},
+		},
+		{
+			name: "values",
+			config: `
+[env]
+"MY VAR" = "1"
+
+[[window]]
+[[window.pane]]
+split = "sideways"
+`,
+			want: []string{"env.MY VAR", "window[0].pane[0].split"},
+		},
+	}
+	for _, c := range cases {
+		path := filepath.Join(t.TempDir(), ".flow.toml")
+		if err := os.WriteFile(path, []byte(c.config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		k := koanf.New(".")
+		if err := k.Load(file.Provider(path), toml.Parser()); err != nil {
+			t.Fatal(err)
+		}
+
+		project, errs := LoadProject(k)
+		if len(errs) != len(c.want) {
+			t.Errorf("%s: expected %d errors but got %d:\n%v", c.name, len(c.want), len(errs), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Key != c.want[i] {
+				t.Errorf("%s: expected error %d about %s but got %v", c.name, i, c.want[i], err)
+			}
+		}
+		if len(c.want) == 0 && (project == nil || project.Env["GOFLAGS"] != "-race") {
+			t.Errorf("%s: expected a project with its env but got %+v", c.name, project)
+		}
+	}
+}
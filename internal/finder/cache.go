@@ -0,0 +1,106 @@
+package finder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/winter-again/flow/internal/atomicfile"
+)
+
+// Cache stores the results of walking roots on disk so that repeated listings, like
+// every <tab> in the picker, don't rescan. An entry is used while it's younger than
+// TTL and none of the dirs read during the walk, e.g. the parent of a new repo at any
+// depth, has been modified since
+type Cache struct {
+	Dir string
+	TTL time.Duration
+}
+
+type cacheEntry struct {
+	Root     string               `json:"root"`
+	ModTimes map[string]time.Time `json:"mod_times"` // mtimes of the dirs read when scanned
+	Scanned  time.Time            `json:"scanned"`
+	Dirs     []string             `json:"dirs"`
+}
+
+// Get returns the cached dirs for root if there's a fresh entry
+func (c *Cache) Get(root Root, opts Options) ([]string, bool) {
+	path, err := rootPath(root)
+	if err != nil {
+		return []string{}, false
+	}
+
+	data, err := os.ReadFile(c.file(path, root, opts))
+	if err != nil {
+		return []string{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return []string{}, false
+	}
+
+	if entry.Root != path || len(entry.ModTimes) == 0 || time.Since(entry.Scanned) >= c.TTL {
+		return []string{}, false
+	}
+	// NOTE: a stat per dir read is still much cheaper than reading them all again
+	for dir, modTime := range entry.ModTimes {
+		info, err := os.Stat(dir)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return []string{}, false
+		}
+	}
+	return entry.Dirs, true
+}
+
+// Put stores the dirs found by walking a root
+func (c *Cache) Put(result Result, opts Options) error {
+	path, err := rootPath(result.Root)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		Root:     path,
+		ModTimes: result.ModTimes,
+		Scanned:  time.Now(),
+		Dirs:     result.Dirs,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't encode find cache: %w", err)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return fmt.Errorf("couldn't create find cache directory: %w", err)
+	}
+	if err := atomicfile.Write(c.file(path, result.Root, opts), data, 0o600); err != nil {
+		return fmt.Errorf("couldn't write find cache: %w", err)
+	}
+	return nil
+}
+
+// file returns the cache file for a root; any setting that changes the walk is part
+// of the key so that editing the config doesn't serve stale results
+func (c *Cache) file(path string, root Root, opts Options) string {
+	key := strings.Join([]string{
+		path,
+		fmt.Sprint(max(1, root.MaxDepth)),
+		strings.Join(opts.Markers, "\x00"),
+		strings.Join(opts.Exclude, "\x00"),
+		strings.Join(root.Exclude, "\x00"),
+		strings.Join(opts.Ignore, "\x00"),
+		fmt.Sprint(opts.GitIgnore),
+	}, "\x01")
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// rootPath returns the absolute path of the root
+func rootPath(root Root) (string, error) {
+	return filepath.Abs(ExpandPath(root.Path))
+}
@@ -0,0 +1,94 @@
+package finder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "api", "web", "org/tools")
+	cache := &Cache{Dir: filepath.Join(t.TempDir(), "find"), TTL: time.Hour}
+	r := Root{Path: root, MaxDepth: 2}
+	opts := Options{Markers: []string{".git"}}
+
+	if _, ok := cache.Get(r, opts); ok {
+		t.Fatal("Expected empty cache to miss")
+	}
+
+	result := FindAll(context.Background(), []Root{r}, opts)[0]
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if err := cache.Put(result, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, ok := cache.Get(r, opts)
+	if !ok || !slices.Equal(got, result.Dirs) {
+		t.Errorf("Expected cache hit with %v but got %v (%v)", result.Dirs, got, ok)
+	}
+
+	if _, ok := cache.Get(Root{Path: root, MaxDepth: 1}, opts); ok {
+		t.Error("Expected different max depth to miss")
+	}
+	if _, ok := cache.Get(r, Options{Markers: []string{"go.mod"}}); ok {
+		t.Error("Expected different markers to miss")
+	}
+
+	// NOTE: adding a dir changes its parent's mtime; set it explicitly so the test
+	// doesn't depend on filesystem timestamp granularity
+	touch := func(dir string) {
+		t.Helper()
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(dir, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	makeTree(t, root, "org/docs")
+	touch(filepath.Join(root, "org"))
+	if _, ok := cache.Get(r, opts); ok {
+		t.Error("Expected modified nested dir to miss")
+	}
+
+	result = FindAll(context.Background(), []Root{r}, opts)[0]
+	if err := cache.Put(result, opts); err != nil {
+		t.Fatal(err)
+	}
+	makeTree(t, root, "docs")
+	touch(root)
+	if _, ok := cache.Get(r, opts); ok {
+		t.Error("Expected modified root to miss")
+	}
+
+	expired := &Cache{Dir: cache.Dir, TTL: time.Nanosecond}
+	if err := expired.Put(result, opts); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok := expired.Get(r, opts); ok {
+		t.Error("Expected expired entry to miss")
+	}
+}
+
+func TestFindAllErrors(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "api")
+
+	results := FindAll(context.Background(), []Root{
+		{Path: filepath.Join(root, "missing")},
+		{Path: root},
+	}, Options{Workers: 1})
+	if len(results) != 2 {
+		t.Fatalf("Expected a result per root but got %v", results)
+	}
+	if results[0].Err == nil {
+		t.Error("Expected error for missing root")
+	}
+	if results[1].Err != nil || !slices.Equal(results[1].Dirs, []string{filepath.Join(root, "api")}) {
+		t.Errorf("Expected other roots to still be listed but got %+v", results[1])
+	}
+}
@@ -0,0 +1,223 @@
+package finder
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Root is a directory to look for session candidates under
+type Root struct {
+	Path     string   `koanf:"path"`
+	MaxDepth int      `koanf:"max_depth"` // how many levels below Path to descend; 1 lists only its children
+	Exclude  []string `koanf:"exclude"`   // gitignore-style patterns of dirs to skip, in addition to Options.Exclude
+}
+
+type Options struct {
+	Markers   []string // files or dirs whose presence makes a dir a project root
+	Exclude   []string // gitignore-style patterns of dirs to skip under every root
+	Ignore    []string // lines of the global ignore file, applied like Exclude
+	GitIgnore bool     // honor .gitignore and .ignore files in the dirs walked
+	Workers   int      // max dirs read at once across all roots; defaults to the number of CPUs
+}
+
+// Result is the outcome of walking one root
+type Result struct {
+	Root     Root
+	Dirs     []string
+	ModTimes map[string]time.Time // mtimes of the dirs read while walking, root included
+	Err      error
+}
+
+// Find walks root and returns the candidate dirs under it: project roots, which
+// aren't descended into, dirs at the maximum depth, and dirs with no subdirs
+func Find(ctx context.Context, root Root, opts Options) ([]string, error) {
+	result := FindAll(ctx, []Root{root}, opts)[0]
+	return result.Dirs, result.Err
+}
+
+// FindAll walks the roots concurrently, sharing one pool of workers. Results are
+// in the same order as roots and a failing root doesn't affect the others. Walks stop
+// early with ctx's error once it's done
+func FindAll(ctx context.Context, roots []Root, opts Options) []Result {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	sem := make(chan struct{}, workers)
+
+	results := make([]Result, len(roots))
+	var wg sync.WaitGroup
+	for i, root := range roots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dirs, modTimes, err := walkRoot(ctx, root, opts, sem)
+			results[i] = Result{Root: root, Dirs: dirs, ModTimes: modTimes, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// walkRoot walks a single root, using sem to bound the dirs read at once. Returns the
+// dirs found along with the mtimes of the dirs read
+func walkRoot(ctx context.Context, root Root, opts Options, sem chan struct{}) ([]string, map[string]time.Time, error) {
+	path, err := filepath.Abs(ExpandPath(root.Path))
+	if err != nil {
+		return []string{}, nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return []string{}, nil, err
+	}
+	if !info.IsDir() {
+		return []string{}, nil, fmt.Errorf("%s is not a directory", path)
+	}
+
+	patterns := slices.Concat(opts.Ignore, opts.Exclude, root.Exclude)
+	w := &walker{
+		maxDepth:  max(1, root.MaxDepth),
+		markers:   opts.Markers,
+		exclude:   &ignorer{rules: parseIgnore(path, patterns)},
+		gitIgnore: opts.GitIgnore,
+		sem:       sem,
+		modTimes:  make(map[string]time.Time),
+	}
+	ig := w.ignorerFor(path, &ignorer{})
+	subdirs, err := w.subdirs(path, ig)
+	if err != nil {
+		return []string{}, nil, err
+	}
+	w.walk(ctx, subdirs, 1, ig)
+	w.wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return []string{}, nil, err
+	}
+
+	// NOTE: workers finish in any order
+	slices.Sort(w.found)
+	return w.found, w.modTimes, nil
+}
+
+type walker struct {
+	maxDepth  int
+	markers   []string
+	exclude   *ignorer // config and global ignore file patterns, which ignore files can't override
+	gitIgnore bool
+	sem       chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	found     []string
+	modTimes  map[string]time.Time // mtimes of the dirs read, taken before reading them
+}
+
+// walk visits subdirs, which are at the given depth below the root and were listed
+// using ig. Subtrees are handed to another goroutine while workers are free and
+// walked inline otherwise
+func (w *walker) walk(ctx context.Context, subdirs []string, depth int, ig *ignorer) {
+	for _, sub := range subdirs {
+		if ctx.Err() != nil {
+			return
+		}
+		if depth >= w.maxDepth || w.isProject(sub) {
+			w.add(sub)
+			continue
+		}
+
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func() {
+				defer w.wg.Done()
+				defer func() { <-w.sem }()
+				w.descend(ctx, sub, depth, ig)
+			}()
+		default:
+			w.descend(ctx, sub, depth, ig)
+		}
+	}
+}
+
+// descend walks into sub, which is at the given depth below the root
+func (w *walker) descend(ctx context.Context, sub string, depth int, ig *ignorer) {
+	ig = w.ignorerFor(sub, ig)
+	// NOTE: dirs that can't be read are still candidates; they just can't be descended into
+	children, err := w.subdirs(sub, ig)
+	if err != nil || len(children) == 0 {
+		w.add(sub)
+		return
+	}
+	w.walk(ctx, children, depth+1, ig)
+}
+
+// ignorerFor returns the ignorer for the contents of dir given the one of its parent
+func (w *walker) ignorerFor(dir string, parent *ignorer) *ignorer {
+	if !w.gitIgnore {
+		return parent
+	}
+	return parent.child(dir)
+}
+
+func (w *walker) add(dir string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.found = append(w.found, dir)
+}
+
+// subdirs lists the dirs, including symlinks to dirs, directly in dir that aren't
+// excluded or ignored by ig
+func (w *walker) subdirs(dir string, ig *ignorer) ([]string, error) {
+	// NOTE: a dir changing while it's read gets a newer mtime than the one recorded
+	info, err := os.Stat(dir)
+	if err != nil {
+		return []string{}, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []string{}, err
+	}
+	w.mu.Lock()
+	w.modTimes[dir] = info.ModTime()
+	w.mu.Unlock()
+
+	var dirs []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err := os.Stat(path)
+			isDir = err == nil && info.IsDir()
+		}
+		if isDir && !w.exclude.ignored(path) && !ig.ignored(path) {
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs, nil
+}
+
+// isProject reports whether dir contains any of the markers
+func (w *walker) isProject(dir string) bool {
+	for _, marker := range w.markers {
+		if _, err := os.Lstat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandPath expands a leading ~ and environment variables such as $HOME in path
+func ExpandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return os.ExpandEnv(path)
+}
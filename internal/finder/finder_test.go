@@ -0,0 +1,117 @@
+package finder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// makeTree creates the dirs, and files for paths ending in a marker, under root
+func makeTree(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		full := filepath.Join(root, path)
+		if filepath.Ext(path) != "" || filepath.Base(path) == "go.mod" {
+			if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(full, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(full, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root,
+		"notes.txt",
+		"scratch",
+		"orgA/api/.git",
+		"orgA/api/cmd/server",
+		"orgA/web/package.json",
+		"orgA/web/node_modules/dep",
+		"orgB/tools/go.mod",
+		"orgB/docs/drafts/old",
+		"archive/legacy/src",
+	)
+	opts := Options{Markers: []string{".git", "go.mod", "package.json"}}
+	abs := func(paths ...string) []string {
+		for i, path := range paths {
+			paths[i] = filepath.Join(root, path)
+		}
+		return paths
+	}
+
+	cases := []struct {
+		name string
+		root Root
+		want []string
+	}{
+		{
+			name: "children only",
+			root: Root{Path: root, MaxDepth: 1},
+			want: abs("archive", "orgA", "orgB", "scratch"),
+		},
+		{
+			name: "projects are not descended into",
+			root: Root{Path: root, MaxDepth: 3},
+			want: abs("archive/legacy/src", "orgA/api", "orgA/web", "orgB/docs/drafts", "orgB/tools", "scratch"),
+		},
+		{
+			name: "exclude by name and relative path",
+			root: Root{Path: root, MaxDepth: 2, Exclude: []string{"archive", "orgB/doc*"}},
+			want: abs("orgA/api", "orgA/web", "orgB/tools", "scratch"),
+		},
+	}
+	for _, c := range cases {
+		got, err := Find(context.Background(), c.root, opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: expected %v but got %v", c.name, c.want, got)
+		}
+	}
+
+	if _, err := Find(context.Background(), Root{Path: filepath.Join(root, "notes.txt")}, opts); err == nil {
+		t.Error("Expected error for root that isn't a directory")
+	}
+	if _, err := Find(context.Background(), Root{Path: filepath.Join(root, "missing")}, opts); err == nil {
+		t.Error("Expected error for missing root")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Find(ctx, Root{Path: root, MaxDepth: 3}, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled walk to fail with context.Canceled but got %v", err)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FLOW_TEST_DIR", "/srv/code")
+
+	cases := map[string]string{
+		"~":                 home,
+		"~/code":            filepath.Join(home, "code"),
+		"$FLOW_TEST_DIR/go": "/srv/code/go",
+		"/abs/~user":        "/abs/~user",
+	}
+	for in, want := range cases {
+		if got := ExpandPath(in); got != want {
+			t.Errorf("ExpandPath(%q): expected %q but got %q", in, want, got)
+		}
+	}
+}
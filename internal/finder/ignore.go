@@ -0,0 +1,168 @@
+package finder
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are read from every dir walked when Options.GitIgnore is set
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// ignoreRule is one gitignore pattern, relative to the dir of the file it came from
+type ignoreRule struct {
+	base   string
+	re     *regexp.Regexp
+	negate bool
+}
+
+// ignorer decides which dirs to skip. It's never modified once built, so walkers
+// on different goroutines can share it
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// parseIgnore parses gitignore syntax. Patterns without a slash match a name at any
+// depth below base; others are anchored to base. Since only dirs are walked, a
+// trailing slash changes nothing
+func parseIgnore(base string, lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		negate := false
+		if strings.HasPrefix(line, "!") {
+			negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		line = strings.TrimSuffix(line, "/")
+		if line == "" {
+			continue
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		rules = append(rules, ignoreRule{base: base, re: re, negate: negate})
+	}
+	return rules
+}
+
+// globToRegexp translates a gitignore glob into a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// with returns an ignorer that also applies rules, which take precedence
+func (ig *ignorer) with(rules []ignoreRule) *ignorer {
+	if len(rules) == 0 {
+		return ig
+	}
+	return &ignorer{rules: append(append([]ignoreRule{}, ig.rules...), rules...)}
+}
+
+// child returns the ignorer for the contents of dir, adding the rules of its ignore files
+func (ig *ignorer) child(dir string) *ignorer {
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		lines, err := readLines(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, parseIgnore(dir, lines)...)
+	}
+	return ig.with(rules)
+}
+
+// ignored reports whether path is ignored; like git, the last matching rule wins
+func (ig *ignorer) ignored(path string) bool {
+	ignored := false
+	for _, rule := range ig.rules {
+		rel, err := filepath.Rel(rule.base, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rule.re.MatchString(filepath.ToSlash(rel)) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ReadIgnoreFile reads the patterns of an ignore file; a missing file has none
+func ReadIgnoreFile(path string) ([]string, error) {
+	lines, err := readLines(path)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	return lines, err
+}
+
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return []string{}, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
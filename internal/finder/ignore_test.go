@@ -0,0 +1,116 @@
+package finder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	base := "/src"
+	ig := &ignorer{rules: parseIgnore(base, []string{
+		"# comment",
+		"",
+		"node_modules/",
+		"/build",
+		"docs/*/old",
+		"**/cache",
+		"vendor/**",
+		"gen*",
+		"!generated-keep",
+		`\#hash`,
+	})}
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/src/node_modules", true},
+		{"/src/web/node_modules", true},
+		{"/src/build", true},
+		{"/src/web/build", false},
+		{"/src/docs/v1/old", true},
+		{"/src/docs/v1/v2/old", false},
+		{"/src/a/b/cache", true},
+		{"/src/vendor", false},
+		{"/src/vendor/pkg", true},
+		{"/src/generated", true},
+		{"/src/generated-keep", false},
+		{"/src/#hash", true},
+		{"/src/comment", false},
+		{"/other/node_modules", false},
+		{"/src", false},
+	}
+	for _, c := range cases {
+		if got := ig.ignored(c.path); got != c.want {
+			t.Errorf("Expected ignored(%s) to be %v but got %v", c.path, c.want, got)
+		}
+	}
+}
+
+func TestFindIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root,
+		"mono/app/src",
+		"mono/node_modules/dep",
+		"mono/target/debug",
+		"mono/lib/vendor/x",
+		"mono/lib/core",
+		"mono/keep/me",
+		"scratch/tmp",
+	)
+	write := func(path, content string) {
+		if err := os.WriteFile(filepath.Join(root, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("mono/.gitignore", "node_modules\n/target\nkeep\n")
+	write("mono/lib/.ignore", "vendor/\n")
+	write("mono/keep/.gitignore", "!me\n")
+
+	abs := func(paths ...string) []string {
+		for i, path := range paths {
+			paths[i] = filepath.Join(root, path)
+		}
+		return paths
+	}
+	cases := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "ignore files off",
+			opts: Options{},
+			want: abs("mono/app/src", "mono/keep/me", "mono/lib/core", "mono/lib/vendor", "mono/node_modules/dep", "mono/target/debug", "scratch/tmp"),
+		},
+		{
+			name: "ignore files on",
+			opts: Options{GitIgnore: true},
+			want: abs("mono/app/src", "mono/lib/core", "scratch/tmp"),
+		},
+		{
+			name: "global ignore file and exclude",
+			opts: Options{GitIgnore: true, Ignore: []string{"tmp"}, Exclude: []string{"app"}},
+			want: abs("mono/lib/core", "scratch"),
+		},
+	}
+	for _, c := range cases {
+		got, err := Find(context.Background(), Root{Path: root, MaxDepth: 3}, c.opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: expected %v but got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	lines, err := ReadIgnoreFile(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(lines) != 0 {
+		t.Errorf("Expected no patterns for a missing file but got %v, %v", lines, err)
+	}
+}
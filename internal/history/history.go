@@ -0,0 +1,225 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/winter-again/flow/internal/atomicfile"
+)
+
+// Kind separates the things flow keeps history for
+type Kind string
+
+const (
+	Sessions Kind = "sessions"
+	Dirs     Kind = "dirs"
+)
+
+// Sort methods
+const (
+	SortAlpha    = "alpha"
+	SortRecency  = "recency"
+	SortFrecency = "frecency"
+)
+
+// maxRank bounds the total rank per kind; once exceeded, every entry is aged
+// so that old favorites eventually make room for new ones
+const maxRank = 10000
+
+type Entry struct {
+	Rank       float64   `json:"rank"`        // number of visits, decayed by aging
+	LastAccess time.Time `json:"last_access"` // time of the most recent visit
+}
+
+// maxStack bounds the number of visits kept on the stack
+const maxStack = 50
+
+// Visit is a switch to a session, with what it takes to recreate the session
+type Visit struct {
+	Session string    `json:"session"`
+	Path    string    `json:"path,omitempty"`   // working directory of the session
+	Server  string    `json:"server,omitempty"` // socket path of the session's server
+	Time    time.Time `json:"time"`
+}
+
+// Store is the on-disk record of visited sessions and dirs
+type Store struct {
+	path    string
+	Entries map[Kind]map[string]*Entry `json:"entries"`
+	Stack   []Visit                    `json:"stack"` // most recent first; a session is only on it once
+}
+
+// Load reads the store at path; a missing file is an empty store
+func Load(path string) (*Store, error) {
+	store := &Store{
+		path:    path,
+		Entries: make(map[Kind]map[string]*Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return store, fmt.Errorf("couldn't read history: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return store, fmt.Errorf("couldn't decode history %s: %w", path, err)
+	}
+	if store.Entries == nil {
+		store.Entries = make(map[Kind]map[string]*Entry)
+	}
+	return store, nil
+}
+
+// Save writes the store back to the path it was loaded from
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("couldn't create history directory: %w", err)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("couldn't encode history: %w", err)
+	}
+
+	// NOTE: concurrent flow processes never read a partial file, but the last one to save
+	// wins, so a visit recorded by another process in the meantime can be lost
+	if err := atomicfile.Write(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("couldn't write history: %w", err)
+	}
+	return nil
+}
+
+// Add records a visit to key
+func (s *Store) Add(kind Kind, key string, now time.Time) {
+	entries, ok := s.Entries[kind]
+	if !ok {
+		entries = make(map[string]*Entry)
+		s.Entries[kind] = entries
+	}
+
+	entry, ok := entries[key]
+	if !ok {
+		entry = &Entry{}
+		entries[key] = entry
+	}
+	entry.Rank++
+	entry.LastAccess = now
+
+	s.age(kind)
+}
+
+// Push puts a visit on top of the stack, removing the earlier visit to the same session.
+// If the visit has no path, the path of the earlier visit is kept
+func (s *Store) Push(visit Visit) {
+	i := slices.IndexFunc(s.Stack, func(v Visit) bool {
+		return v.Session == visit.Session && v.Server == visit.Server
+	})
+	if i >= 0 {
+		if visit.Path == "" {
+			visit.Path = s.Stack[i].Path
+		}
+		s.Stack = slices.Delete(s.Stack, i, i+1)
+	}
+
+	s.Stack = slices.Insert(s.Stack, 0, visit)
+	if len(s.Stack) > maxStack {
+		s.Stack = s.Stack[:maxStack]
+	}
+}
+
+// Previous returns the nth most recent visit to a session of the server before the
+// latest one, so 1 is the session visited before the current one
+func (s *Store) Previous(server string, n int) (Visit, bool) {
+	for _, visit := range s.Stack {
+		if visit.Server != server {
+			continue
+		}
+		if n == 0 {
+			return visit, true
+		}
+		n--
+	}
+	return Visit{}, false
+}
+
+// age scales down every rank of the kind once their total exceeds maxRank,
+// dropping entries that decay below a single visit
+func (s *Store) age(kind Kind) {
+	total := 0.0
+	for _, entry := range s.Entries[kind] {
+		total += entry.Rank
+	}
+	if total <= maxRank {
+		return
+	}
+
+	for key, entry := range s.Entries[kind] {
+		entry.Rank *= 0.9
+		if entry.Rank < 1 {
+			delete(s.Entries[kind], key)
+		}
+	}
+}
+
+// Score combines how often and how recently key was visited
+func (s *Store) Score(kind Kind, key string, now time.Time) float64 {
+	entry, ok := s.Entries[kind][key]
+	if !ok {
+		return 0
+	}
+
+	age := now.Sub(entry.LastAccess)
+	switch {
+	case age < time.Hour:
+		return entry.Rank * 4
+	case age < 24*time.Hour:
+		return entry.Rank * 2
+	case age < 7*24*time.Hour:
+		return entry.Rank * 0.5
+	default:
+		return entry.Rank * 0.25
+	}
+}
+
+// Sort orders items in place using the given method. Items are sorted alphabetically
+// first so that items without history, or with equal scores, stay in a stable order
+func (s *Store) Sort(kind Kind, items []string, method string, now time.Time) error {
+	slices.Sort(items)
+
+	switch method {
+	case SortAlpha:
+	case SortRecency:
+		slices.SortStableFunc(items, func(a, b string) int {
+			return s.lastAccess(kind, b).Compare(s.lastAccess(kind, a))
+		})
+	case SortFrecency:
+		slices.SortStableFunc(items, func(a, b string) int {
+			sa, sb := s.Score(kind, a, now), s.Score(kind, b, now)
+			switch {
+			case sa > sb:
+				return -1
+			case sa < sb:
+				return 1
+			default:
+				return 0
+			}
+		})
+	default:
+		return fmt.Errorf("unknown sort method %q: expected one of %s, %s or %s", method, SortAlpha, SortRecency, SortFrecency)
+	}
+	return nil
+}
+
+func (s *Store) lastAccess(kind Kind, key string) time.Time {
+	if entry, ok := s.Entries[kind][key]; ok {
+		return entry.LastAccess
+	}
+	return time.Time{}
+}
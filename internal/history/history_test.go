@@ -0,0 +1,131 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow", "history.json")
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected missing file to load as empty store but got %v", err)
+	}
+	store.Add(Sessions, "api", now)
+	store.Add(Sessions, "api", now)
+	store.Add(Dirs, "/code/api", now)
+	if err := store.Save(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry := loaded.Entries[Sessions]["api"]
+	if entry == nil || entry.Rank != 2 || !entry.LastAccess.Equal(now) {
+		t.Errorf("Expected api session with rank 2 but got %+v", entry)
+	}
+	if loaded.Entries[Dirs]["/code/api"] == nil {
+		t.Error("Expected /code/api dir to be saved")
+	}
+}
+
+func TestSort(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	store, _ := Load(filepath.Join(t.TempDir(), "history.json"))
+
+	// often but long ago
+	for range 10 {
+		store.Add(Sessions, "old-favorite", now.Add(-30*24*time.Hour))
+	}
+	// once, just now
+	store.Add(Sessions, "recent", now.Add(-time.Minute))
+	// a few times today
+	for range 3 {
+		store.Add(Sessions, "daily", now.Add(-2*time.Hour))
+	}
+
+	cases := []struct {
+		method string
+		want   []string
+	}{
+		{SortAlpha, []string{"daily", "never", "old-favorite", "recent", "zzz"}},
+		{SortRecency, []string{"recent", "daily", "old-favorite", "never", "zzz"}},
+		{SortFrecency, []string{"daily", "recent", "old-favorite", "never", "zzz"}},
+	}
+	for _, c := range cases {
+		items := []string{"zzz", "recent", "never", "old-favorite", "daily"}
+		if err := store.Sort(Sessions, items, c.method, now); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !slices.Equal(items, c.want) {
+			t.Errorf("Sort(%s): expected %v but got %v", c.method, c.want, items)
+		}
+	}
+
+	if err := store.Sort(Sessions, []string{"a"}, "random", now); err == nil {
+		t.Error("Expected error for unknown sort method")
+	}
+}
+
+func TestAging(t *testing.T) {
+	now := time.Now()
+	store, _ := Load(filepath.Join(t.TempDir(), "history.json"))
+	store.Add(Dirs, "/rare", now)
+	for range maxRank {
+		store.Add(Dirs, "/busy", now)
+	}
+
+	if _, ok := store.Entries[Dirs]["/rare"]; ok {
+		t.Error("Expected rarely visited entry to be aged out")
+	}
+	if rank := store.Entries[Dirs]["/busy"].Rank; rank >= maxRank {
+		t.Errorf("Expected busy entry to be aged below %d but got %f", maxRank, rank)
+	}
+}
+
+func TestStack(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "history.json")
+	store, _ := Load(path)
+
+	store.Push(Visit{Session: "api", Path: "/code/api", Server: "/tmp/tmux-1000/default", Time: now})
+	store.Push(Visit{Session: "web", Path: "/code/web", Server: "/tmp/tmux-1000/default", Time: now})
+	store.Push(Visit{Session: "api", Server: "/tmp/tmux-1000/client", Time: now})
+	store.Push(Visit{Session: "notes", Server: "/tmp/tmux-1000/default", Time: now})
+	// NOTE: revisiting without a path, e.g. through the picker, keeps the known one
+	store.Push(Visit{Session: "api", Server: "/tmp/tmux-1000/default", Time: now})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for n := 0; ; n++ {
+		visit, ok := store.Previous("/tmp/tmux-1000/default", n)
+		if !ok {
+			break
+		}
+		got = append(got, visit.Session+":"+visit.Path)
+	}
+	want := []string{"api:/code/api", "notes:", "web:/code/web"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected stack %v but got %v", want, got)
+	}
+
+	for i := range maxStack + 10 {
+		store.Push(Visit{Session: fmt.Sprint(i), Time: now})
+	}
+	if len(store.Stack) != maxStack {
+		t.Errorf("Expected stack to be bounded to %d visits but got %d", maxStack, len(store.Stack))
+	}
+}
@@ -0,0 +1,275 @@
+package layout
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// FileName is the name of project files, which describe the sessions of the dir they're
+// in and the dirs below it
+const FileName = ".flow.toml"
+
+// Project describes the session to create for directories matching one of its
+// patterns, or for the directory of a project file
+type Project struct {
+	Match    []string          `koanf:"match"`    // directory paths or globs the project applies to; not in project files
+	Name     string            `koanf:"name"`     // session name; left to the naming strategy if empty
+	Env      map[string]string `koanf:"env"`      // environment variables of the session
+	Commands []string          `koanf:"commands"` // typed into the first pane once the windows are built
+	Hooks    Hooks             `koanf:"hooks"`    // shell commands run around the session
+	Windows  []Window          `koanf:"window"`   // windows to create, in order
+}
+
+// Hooks are shell commands run in the session directory, outside tmux
+type Hooks struct {
+	Create []string `koanf:"create"` // before the session is created, e.g. to start services
+	Switch []string `koanf:"switch"` // whenever flow goes to the session, including after creating it
+}
+
+type Window struct {
+	Name   string `koanf:"name"`   // window name; left to tmux if empty
+	Dir    string `koanf:"dir"`    // working directory, relative to the session directory
+	Layout string `koanf:"layout"` // preset (e.g., "main-vertical") or custom layout string
+	Focus  bool   `koanf:"focus"`  // make this the active window of the session
+	Panes  []Pane `koanf:"pane"`   // panes to create, in order; defaults to a single pane
+}
+
+type Pane struct {
+	Dir   string `koanf:"dir"`   // working directory, relative to the window directory
+	Cmd   string `koanf:"cmd"`   // startup command typed into the pane
+	Split string `koanf:"split"` // "horizontal" for side by side, otherwise top and bottom
+	Focus bool   `koanf:"focus"` // make this the active pane of the window
+}
+
+// Match returns the first project whose patterns match the given directory
+func Match(projects []Project, dir string) (*Project, bool) {
+	for i, project := range projects {
+		for _, pattern := range project.Match {
+			pattern = filepath.Clean(expandHome(pattern))
+			if pattern == dir {
+				return &projects[i], true
+			}
+			if ok, err := filepath.Match(pattern, dir); err == nil && ok {
+				return &projects[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Merge overrides the settings of the project with the ones set in over. Env vars are
+// added to the project's; everything else is replaced
+func (project *Project) Merge(over *Project) {
+	if over.Name != "" {
+		project.Name = over.Name
+	}
+	if len(over.Env) > 0 {
+		env := make(map[string]string, len(project.Env)+len(over.Env))
+		maps.Copy(env, project.Env)
+		maps.Copy(env, over.Env)
+		project.Env = env
+	}
+	if over.Commands != nil {
+		project.Commands = over.Commands
+	}
+	if over.Hooks.Create != nil {
+		project.Hooks.Create = over.Hooks.Create
+	}
+	if over.Hooks.Switch != nil {
+		project.Hooks.Switch = over.Hooks.Switch
+	}
+	if over.Windows != nil {
+		project.Windows = over.Windows
+	}
+}
+
+// EnvList returns the project's env vars as sorted KEY=value pairs
+func (project *Project) EnvList() []string {
+	env := make([]string, 0, len(project.Env))
+	for _, key := range slices.Sorted(maps.Keys(project.Env)) {
+		env = append(env, key+"="+project.Env[key])
+	}
+	return env
+}
+
+// FindFiles returns the project files in dir and its parents up to root, outermost first.
+// If dir isn't inside root, only dir is searched
+func FindFiles(dir string, root string) []string {
+	dir, root = filepath.Clean(dir), filepath.Clean(root)
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		root = dir
+	}
+
+	var files []string
+	for {
+		file := filepath.Join(dir, FileName)
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			files = append(files, file)
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			break
+		}
+		dir = parent
+	}
+	slices.Reverse(files)
+	return files
+}
+
+// Apply builds the project's windows and panes in a freshly created session, then
+// types its commands into the first pane. The session's initial window and pane are
+// reused for the first window and pane
+func Apply(ctx context.Context, server *tmux.Server, session *tmux.Session, project *Project) error {
+	if len(project.Windows) == 0 && len(project.Commands) == 0 {
+		return nil
+	}
+
+	windows, err := server.GetWindows(ctx, session)
+	if err != nil {
+		return err
+	}
+	if len(windows) == 0 {
+		return fmt.Errorf("session %s has no windows", session.Name)
+	}
+	if err := applyWindows(ctx, server, session, project, windows); err != nil {
+		return err
+	}
+
+	if len(project.Commands) == 0 {
+		return nil
+	}
+	panes, err := server.GetPanes(ctx, windows[0])
+	if err != nil {
+		return err
+	}
+	if len(panes) == 0 {
+		return fmt.Errorf("window %s has no panes", windows[0].Id)
+	}
+	for _, command := range project.Commands {
+		if err := server.SendCommand(ctx, panes[0].Id, command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyWindows builds the project's windows and panes, starting with the session's
+// initial window
+func applyWindows(ctx context.Context, server *tmux.Server, session *tmux.Session, project *Project, windows []*tmux.Window) error {
+	if len(project.Windows) == 0 {
+		return nil
+	}
+
+	var err error
+	focusWindow := windows[0].Id
+	for i, w := range project.Windows {
+		windowPath := resolveDir(session.Path, w.Dir)
+		panes := w.Panes
+		if len(panes) == 0 {
+			panes = []Pane{{}}
+		}
+
+		var window *tmux.Window
+		firstPanePath := resolveDir(windowPath, panes[0].Dir)
+		if i == 0 {
+			window = windows[0]
+			if w.Name != "" {
+				if err := server.RenameWindow(ctx, window.Id, w.Name); err != nil {
+					return err
+				}
+			}
+		} else {
+			window, err = server.NewWindow(ctx, session.Id, w.Name, firstPanePath)
+			if err != nil {
+				return err
+			}
+		}
+
+		existing, err := server.GetPanes(ctx, window)
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			return fmt.Errorf("window %s has no panes", window.Id)
+		}
+		if i == 0 && firstPanePath != filepath.Clean(session.Path) {
+			if err := server.RespawnPane(ctx, existing[0].Id, firstPanePath); err != nil {
+				return err
+			}
+		}
+
+		paneIds := []string{existing[0].Id}
+		for _, p := range panes[1:] {
+			pane, err := server.SplitWindow(ctx, paneIds[len(paneIds)-1], resolveDir(windowPath, p.Dir), p.Split == "horizontal")
+			if err != nil {
+				return err
+			}
+			paneIds = append(paneIds, pane.Id)
+
+			// NOTE: with a layout, rebalance after every split so that later splits don't run
+			// out of room, since the layout replaces the splits anyway; custom layout strings
+			// only apply once all of their panes exist. Without one, the splits are the layout
+			if w.Layout != "" {
+				if err := server.SelectLayout(ctx, window.Id, "tiled"); err != nil {
+					return err
+				}
+			}
+		}
+		if w.Layout != "" {
+			if err := server.SelectLayout(ctx, window.Id, w.Layout); err != nil {
+				return err
+			}
+		}
+
+		focusPane := paneIds[0]
+		for j, p := range panes {
+			if p.Cmd != "" {
+				if err := server.SendCommand(ctx, paneIds[j], p.Cmd); err != nil {
+					return err
+				}
+			}
+			if p.Focus {
+				focusPane = paneIds[j]
+			}
+		}
+		if err := server.SelectPane(ctx, focusPane); err != nil {
+			return err
+		}
+		if w.Focus {
+			focusWindow = window.Id
+		}
+	}
+	return server.SelectWindow(ctx, focusWindow)
+}
+
+// resolveDir resolves dir relative to base, expanding a leading ~
+func resolveDir(base string, dir string) string {
+	if dir == "" {
+		return filepath.Clean(base)
+	}
+	dir = expandHome(dir)
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(base, dir)
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
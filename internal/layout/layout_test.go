@@ -0,0 +1,125 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	projects := []Project{
+		{Match: []string{"/home/user/code/api"}},
+		{Match: []string{"/home/user/work/*", "/srv/app"}},
+	}
+
+	cases := []struct {
+		dir     string
+		want    int
+		matches bool
+	}{
+		{"/home/user/code/api", 0, true},
+		{"/home/user/work/web", 1, true},
+		{"/srv/app", 1, true},
+		{"/home/user/work/web/nested", 0, false},
+		{"/home/user/code", 0, false},
+	}
+	for _, c := range cases {
+		project, ok := Match(projects, c.dir)
+		if ok != c.matches {
+			t.Errorf("Match(%q): expected match %v but got %v", c.dir, c.matches, ok)
+			continue
+		}
+		if ok && project != &projects[c.want] {
+			t.Errorf("Match(%q): expected project %d but got %+v", c.dir, c.want, *project)
+		}
+	}
+}
+
+func TestResolveDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		base, dir, want string
+	}{
+		{"/code/api", "", "/code/api"},
+		{"/code/api", "cmd/server", "/code/api/cmd/server"},
+		{"/code/api", "/var/log", "/var/log"},
+		{"/code/api", "~/notes", filepath.Join(home, "notes")},
+	}
+	for _, c := range cases {
+		if got := resolveDir(c.base, c.dir); got != c.want {
+			t.Errorf("resolveDir(%q, %q): expected %q but got %q", c.base, c.dir, c.want, got)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	project := &Project{
+		Name:     "api",
+		Env:      map[string]string{"A": "1", "B": "2"},
+		Commands: []string{"make"},
+		Hooks:    Hooks{Create: []string{"docker compose up -d"}},
+		Windows:  []Window{{Name: "editor"}},
+	}
+	project.Merge(&Project{
+		Env:   map[string]string{"B": "3", "C": "4"},
+		Hooks: Hooks{Switch: []string{"git fetch"}},
+	})
+
+	if project.Name != "api" {
+		t.Errorf("Expected name %q but got %q", "api", project.Name)
+	}
+	if got, want := project.EnvList(), []string{"A=1", "B=3", "C=4"}; !slices.Equal(got, want) {
+		t.Errorf("Expected env %v but got %v", want, got)
+	}
+	if !slices.Equal(project.Commands, []string{"make"}) {
+		t.Errorf("Expected commands to be kept but got %v", project.Commands)
+	}
+	if len(project.Hooks.Create) != 1 || len(project.Hooks.Switch) != 1 {
+		t.Errorf("Expected both hooks but got %+v", project.Hooks)
+	}
+	if len(project.Windows) != 1 {
+		t.Errorf("Expected windows to be kept but got %+v", project.Windows)
+	}
+}
+
+func TestFindFiles(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "work", "api", "cmd")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{root, filepath.Join(root, "work"), filepath.Join(root, "work", "api")} {
+		if err := os.WriteFile(filepath.Join(d, FileName), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		dir, root string
+		want      []string
+	}{
+		{dir, filepath.Join(root, "work"), []string{
+			filepath.Join(root, "work", FileName),
+			filepath.Join(root, "work", "api", FileName),
+		}},
+		{filepath.Join(root, "work", "api"), root, []string{
+			filepath.Join(root, FileName),
+			filepath.Join(root, "work", FileName),
+			filepath.Join(root, "work", "api", FileName),
+		}},
+		{filepath.Join(root, "work", "api"), "/elsewhere", []string{
+			filepath.Join(root, "work", "api", FileName),
+		}},
+		{dir, dir, nil},
+	}
+	for _, c := range cases {
+		if got := FindFiles(c.dir, c.root); !slices.Equal(got, c.want) {
+			t.Errorf("FindFiles(%q, %q): expected %v but got %v", c.dir, c.root, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package naming
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// Strategies for deriving the name of a session from its dir
+const (
+	Basename  = "basename"   // e.g. "api" for ~/work/api
+	Parent    = "parent"     // e.g. "work/api" for ~/work/api
+	GitRemote = "git-remote" // e.g. "acme/api" for a clone of github.com/acme/api
+	Template  = "template"   // a text/template over Dir
+)
+
+// sep joins the parts of a name made from several dirs
+const sep = "/"
+
+type Options struct {
+	Strategy string // one of the strategies; defaults to Basename
+	Template string // used by the template strategy, e.g. "{{.Parent}}-{{.Base}}"
+	Remote   string // git remote used by the git-remote strategy and templates; defaults to "origin"
+}
+
+// Dir is what names are made from, and what templates can refer to
+type Dir struct {
+	Path   string // absolute path of the dir
+	Base   string // name of the dir
+	Parent string // name of the dir's parent
+	Owner  string // owner of the git remote, e.g. "acme"; empty if there is none
+	Repo   string // name of the git remote's repo, e.g. "api"; empty if there is none
+}
+
+// Name derives the name of a session for a dir. The result is sanitized for tmux
+func Name(ctx context.Context, dir string, opts Options) (string, error) {
+	d := Dir{
+		Path:   dir,
+		Base:   filepath.Base(dir),
+		Parent: filepath.Base(filepath.Dir(dir)),
+	}
+
+	var name string
+	switch opts.Strategy {
+	case "", Basename:
+		name = d.Base
+	case Parent:
+		name = d.Parent + sep + d.Base
+		if d.Parent == string(filepath.Separator) {
+			name = d.Base
+		}
+	case GitRemote:
+		d.Owner, d.Repo = remote(ctx, dir, opts.Remote)
+		name = d.Base
+		if d.Repo != "" {
+			name = d.Owner + sep + d.Repo
+		}
+	case Template:
+		tmpl, err := parseTemplate(opts.Template)
+		if err != nil {
+			return "", err
+		}
+		d.Owner, d.Repo = remote(ctx, dir, opts.Remote)
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, d); err != nil {
+			return "", fmt.Errorf("couldn't execute name template: %w", err)
+		}
+		name = strings.TrimSpace(buf.String())
+		if name == "" {
+			return "", fmt.Errorf("name template gives an empty name for %s", dir)
+		}
+	default:
+		return "", fmt.Errorf("unknown naming strategy %q", opts.Strategy)
+	}
+	return tmux.SanitizeSessionName(name), nil
+}
+
+// Validate checks the strategy and, for the template strategy, the template
+func Validate(opts Options) error {
+	switch opts.Strategy {
+	case "", Basename, Parent, GitRemote:
+		return nil
+	case Template:
+		_, err := parseTemplate(opts.Template)
+		return err
+	}
+	return fmt.Errorf("unknown naming strategy %q", opts.Strategy)
+}
+
+// parseTemplate parses the template of the template strategy
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, errors.New("the template naming strategy needs a template")
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse name template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Unique returns name if it isn't taken, or else the first variant of it that isn't.
+// Names made from the end of dir's path get more of it, e.g. "api" becomes "work/api"
+// and then "home/work/api"; after that, or for other names, a number is appended, e.g.
+// "api-2". The result only depends on what's taken, so the same dir gets the same name
+// as long as the sessions stay the same
+func Unique(name string, dir string, taken func(string) bool) string {
+	if !taken(name) {
+		return name
+	}
+
+	parts := strings.FieldsFunc(filepath.Clean(dir), func(r rune) bool { return r == filepath.Separator })
+	for n := 1; n <= len(parts); n++ {
+		if tmux.SanitizeSessionName(strings.Join(parts[len(parts)-n:], sep)) != name {
+			continue
+		}
+		for m := n + 1; m <= len(parts); m++ {
+			candidate := tmux.SanitizeSessionName(strings.Join(parts[len(parts)-m:], sep))
+			if !taken(candidate) {
+				return candidate
+			}
+		}
+		break
+	}
+
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// remote returns the owner and repo of a git remote of the dir; empty if the dir
+// isn't in a git repo or the remote doesn't exist
+func remote(ctx context.Context, dir string, name string) (string, string) {
+	if name == "" {
+		name = "origin"
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "remote", "get-url", name).Output()
+	if err != nil {
+		return "", ""
+	}
+	return parseRemote(strings.TrimSpace(string(out)))
+}
+
+// parseRemote splits a remote URL, e.g. "git@github.com:acme/api.git" or
+// "https://github.com/acme/api", into its owner and repo
+func parseRemote(url string) (string, string) {
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	} else if _, rest, ok := strings.Cut(url, ":"); ok {
+		// NOTE: scp-like syntax, e.g. git@github.com:acme/api
+		url = rest
+	}
+
+	parts := strings.Split(url, "/")
+	if len(parts) < 2 {
+		return "", ""
+	}
+	owner, repo := parts[len(parts)-2], parts[len(parts)-1]
+	if repo == "" {
+		return "", ""
+	}
+	return owner, repo
+}
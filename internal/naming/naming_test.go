@@ -0,0 +1,88 @@
+package naming
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	ctx := context.Background()
+	notRepo := t.TempDir()
+
+	cases := []struct {
+		name    string
+		dir     string
+		opts    Options
+		want    string
+		wantErr bool
+	}{
+		{name: "default", dir: "/home/user/work/api", want: "api"},
+		{name: "basename", dir: "/home/user/example.com", opts: Options{Strategy: Basename}, want: "example_com"},
+		{name: "parent", dir: "/home/user/work/api", opts: Options{Strategy: Parent}, want: "work/api"},
+		{name: "parent of root dir", dir: "/api", opts: Options{Strategy: Parent}, want: "api"},
+		{name: "git remote outside repo", dir: notRepo, opts: Options{Strategy: GitRemote}, want: filepath.Base(notRepo)},
+		{
+			name: "template",
+			dir:  "/home/user/work/api.v2",
+			opts: Options{Strategy: Template, Template: "{{.Parent}}-{{.Base}}"},
+			want: "work-api_v2",
+		},
+		{name: "empty template", dir: "/home/user/work/api", opts: Options{Strategy: Template}, wantErr: true},
+		{name: "bad template", dir: "/home/user/work/api", opts: Options{Strategy: Template, Template: "{{.Nope}}"}, wantErr: true},
+		{name: "blank template result", dir: "/home/user/work/api", opts: Options{Strategy: Template, Template: "{{.Repo}}"}, wantErr: true},
+		{name: "unknown strategy", dir: "/home/user/work/api", opts: Options{Strategy: "nope"}, wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := Name(ctx, c.dir, c.opts)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: expected error %v but got %v", c.name, c.wantErr, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: expected %q but got %q", c.name, c.want, got)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	cases := []struct {
+		name    string
+		session string
+		dir     string
+		taken   []string
+		want    string
+	}{
+		{"free", "api", "/home/user/work/api", []string{"web"}, "api"},
+		{"basename taken", "api", "/home/user/work/api", []string{"api"}, "work/api"},
+		{"parent taken", "api", "/home/user/work/api", []string{"api", "work/api"}, "user/work/api"},
+		{"parent name taken", "work/api", "/home/user/work/api", []string{"work/api"}, "user/work/api"},
+		{"whole path taken", "api", "/work/api", []string{"api", "work/api"}, "api-2"},
+		{"sanitized", "api_v2", "/home/example.com/api.v2", []string{"api_v2"}, "example_com/api_v2"},
+		{"other name", "acme/api", "/home/user/work/api", []string{"acme/api", "acme/api-2"}, "acme/api-3"},
+	}
+	for _, c := range cases {
+		got := Unique(c.session, c.dir, func(n string) bool { return slices.Contains(c.taken, n) })
+		if got != c.want {
+			t.Errorf("%s: expected %q but got %q", c.name, c.want, got)
+		}
+	}
+}
+
+func TestParseRemote(t *testing.T) {
+	cases := map[string][2]string{
+		"git@github.com:acme/api.git":       {"acme", "api"},
+		"https://github.com/acme/api":       {"acme", "api"},
+		"https://github.com/acme/api.git/":  {"acme", "api"},
+		"ssh://git@host:2222/group/sub/api": {"sub", "api"},
+		"/srv/git/api.git":                  {"git", "api"},
+		"https://github.com":                {"", ""},
+	}
+	for url, want := range cases {
+		owner, repo := parseRemote(url)
+		if owner != want[0] || repo != want[1] {
+			t.Errorf("parseRemote(%q): expected %v but got [%s %s]", url, want, owner, repo)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/winter-again/flow/internal/shell"
+)
+
+// Builtin runs flow's own picker (see Run) inside a tmux popup
+type Builtin struct {
+	cfg Config
+}
+
+func (b *Builtin) Actions() bool {
+	return true
+}
+
+func (b *Builtin) Pick(ctx context.Context, items []string, opts Options) (Result, error) {
+	if len(b.cfg.FlowCmd) == 0 {
+		return Result{}, errors.New("builtin picker needs the flow command")
+	}
+
+	dir, err := os.MkdirTemp("", "flow-pick-")
+	if err != nil {
+		return Result{}, fmt.Errorf("couldn't create picker temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	itemsFile := filepath.Join(dir, "items")
+	outputFile := filepath.Join(dir, "output")
+	if err := os.WriteFile(itemsFile, []byte(strings.Join(items, "\n")), 0o600); err != nil {
+		return Result{}, fmt.Errorf("couldn't write picker items: %w", err)
+	}
+
+	args := append(slices.Clone(b.cfg.FlowCmd),
+		"--items", itemsFile,
+		"--output", outputFile,
+		"--prompt", opts.Prompt,
+		"--header", opts.Header,
+		"--preview", opts.Preview,
+		"--preview-pos", opts.PreviewPos,
+		"--preview-size", strconv.Itoa(opts.PreviewSize),
+	)
+	if err := displayPopup(ctx, b.cfg, shell.Join(args)); err != nil {
+		return Result{}, pickerError(ctx, "builtin picker", err)
+	}
+
+	out, err := os.ReadFile(outputFile)
+	if err != nil {
+		return Result{}, fmt.Errorf("couldn't read picker result: %w", err)
+	}
+	return ParseResult(string(out))
+}
+
+// FormatResult encodes the result of Run for the process waiting on the popup
+func FormatResult(result Result) string {
+	return result.Action.String() + "\n" + result.Selection
+}
+
+// ParseResult is the inverse of FormatResult
+func ParseResult(s string) (Result, error) {
+	action, selection, _ := strings.Cut(s, "\n")
+	a, err := ParseAction(action)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Action: a, Selection: selection}, nil
+}
@@ -0,0 +1,41 @@
+package picker
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Dmenu runs a dmenu-style picker such as dmenu, rofi -dmenu or fuzzel --dmenu:
+// items are written to its stdin and the selection is read from its stdout
+type Dmenu struct {
+	cfg Config
+}
+
+// Actions is false since dmenu-style pickers only report the selection
+func (d *Dmenu) Actions() bool {
+	return false
+}
+
+func (d *Dmenu) Pick(ctx context.Context, items []string, opts Options) (Result, error) {
+	name := d.cfg.DmenuCmd[0]
+	bin, err := exec.LookPath(name)
+	if err != nil {
+		return Result{}, fmt.Errorf("couldn't find %s in the PATH", name)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, d.cfg.DmenuCmd[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
+
+	out, err := cmd.Output()
+	if err != nil {
+		return Result{}, pickerError(ctx, name, err)
+	}
+
+	selection := strings.TrimSpace(string(out))
+	if selection == "" {
+		return Result{}, ErrCancelled
+	}
+	return Result{Action: ActionSelect, Selection: selection}, nil
+}
@@ -0,0 +1,141 @@
+package picker
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 8
+	bonusBoundary    = 8
+	bonusFirstChar   = 4
+	penaltyGap       = 1
+)
+
+// Match is an item that matched the query, with the positions of the matched runes
+type Match struct {
+	Item      string
+	Index     int   // index of the item in the original list
+	Score     int   // higher is better
+	Positions []int // rune positions in Item that matched the query
+}
+
+// Filter returns the items matching the query, best matches first. Items with equal
+// scores keep their original order. Space-separated terms must all match; matching
+// is case-insensitive unless a term contains an uppercase letter
+func Filter(query string, items []string) []Match {
+	terms := strings.Fields(query)
+
+	var matches []Match
+	for i, item := range items {
+		m := Match{Item: item, Index: i}
+		ok := true
+		for _, term := range terms {
+			score, positions, found := fuzzyMatch(term, item)
+			if !found {
+				ok = false
+				break
+			}
+			m.Score += score
+			m.Positions = append(m.Positions, positions...)
+		}
+		if !ok {
+			continue
+		}
+		slices.Sort(m.Positions)
+		m.Positions = slices.Compact(m.Positions)
+		matches = append(matches, m)
+	}
+
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return b.Score - a.Score
+	})
+	return matches
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in order in text. It finds the
+// first place the pattern ends, then walks backwards to the tightest start so that
+// "abc" in "a_abc" matches the trailing run rather than the scattered one
+func fuzzyMatch(pattern string, text string) (int, []int, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, []int{}, true
+	}
+
+	caseSensitive := slices.ContainsFunc(p, unicode.IsUpper)
+	eq := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// forward: earliest end of the subsequence
+	pi := 0
+	end := -1
+	for ti := 0; ti < len(t); ti++ {
+		if eq(p[pi], t[ti]) {
+			pi++
+			if pi == len(p) {
+				end = ti
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, []int{}, false
+	}
+
+	// backward: latest start that still matches up to end
+	positions := make([]int, len(p))
+	pi = len(p) - 1
+	for ti := end; ti >= 0 && pi >= 0; ti-- {
+		if eq(p[pi], t[ti]) {
+			positions[pi] = ti
+			pi--
+		}
+	}
+
+	return score(t, positions), positions, true
+}
+
+// score rewards matches that are consecutive, start words or start the text,
+// and penalizes the gaps between matched runes
+func score(text []rune, positions []int) int {
+	total := 0
+	for i, pos := range positions {
+		total += scoreMatch
+		if pos == 0 {
+			total += bonusFirstChar
+		}
+		if isBoundary(text, pos) {
+			total += bonusBoundary
+		}
+		if i > 0 {
+			gap := pos - positions[i-1] - 1
+			if gap == 0 {
+				total += bonusConsecutive
+			} else {
+				total -= gap * penaltyGap
+			}
+		}
+	}
+	return total
+}
+
+// isBoundary reports whether the rune at pos starts a word, e.g., follows a path
+// separator or punctuation, or is an uppercase letter after a lowercase one
+func isBoundary(text []rune, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	prev, curr := text[pos-1], text[pos]
+	switch prev {
+	case '/', '-', '_', '.', ' ', ':':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(curr)
+}
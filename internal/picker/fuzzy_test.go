@@ -0,0 +1,66 @@
+package picker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	cases := []struct {
+		pattern, text string
+		ok            bool
+		positions     []int
+	}{
+		{"abc", "abc", true, []int{0, 1, 2}},
+		{"abc", "a_abc", true, []int{2, 3, 4}},
+		{"fb", "foo/bar", true, []int{0, 4}},
+		{"FB", "foo/bar", false, nil},
+		{"Fb", "Foo/bar", true, []int{0, 4}},
+		{"xyz", "foo/bar", false, nil},
+		{"", "anything", true, []int{}},
+	}
+	for _, c := range cases {
+		_, positions, ok := fuzzyMatch(c.pattern, c.text)
+		if ok != c.ok {
+			t.Errorf("fuzzyMatch(%q, %q): expected match %v but got %v", c.pattern, c.text, c.ok, ok)
+			continue
+		}
+		if ok && !slices.Equal(positions, c.positions) {
+			t.Errorf("fuzzyMatch(%q, %q): expected positions %v but got %v", c.pattern, c.text, c.positions, positions)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := []string{
+		"/home/user/code/flow-old",
+		"/home/user/projects/fzf-lua-wrapper",
+		"/home/user/code/flow",
+		"/home/user/notes",
+	}
+
+	matches := Filter("flow", items)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches but got %v", matches)
+	}
+	// NOTE: equal scores keep the original order
+	if matches[0].Item != items[0] || matches[1].Item != items[2] {
+		t.Errorf("Expected matches in original order but got %v", matches)
+	}
+
+	matches = Filter("code notes", items)
+	if len(matches) != 0 {
+		t.Errorf("Expected every term to be required but got %v", matches)
+	}
+
+	matches = Filter("", items)
+	if len(matches) != len(items) {
+		t.Errorf("Expected empty query to match everything but got %v", matches)
+	}
+
+	// word boundaries beat scattered matches
+	matches = Filter("fw", []string{"/x/fooxxxxwxx", "/x/fzf-wrapper"})
+	if len(matches) != 2 || matches[0].Item != "/x/fzf-wrapper" {
+		t.Errorf("Expected boundary match to rank first but got %v", matches)
+	}
+}
@@ -0,0 +1,153 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/winter-again/flow/internal/shell"
+)
+
+// FzfTmux runs fzf through its fzf-tmux wrapper script
+type FzfTmux struct {
+	cfg Config
+}
+
+func (f *FzfTmux) Actions() bool {
+	return true
+}
+
+func (f *FzfTmux) Pick(ctx context.Context, items []string, opts Options) (Result, error) {
+	fzfTmux, err := exec.LookPath("fzf-tmux") // NOTE: fzf-tmux is wrapper script from fzf
+	if err != nil {
+		return Result{}, errors.New("couldn't find fzf-tmux in the PATH")
+	}
+
+	args := append([]string{
+		"-p", // popup window size, req. tmux 3.2+
+		fmt.Sprintf("%s,%s", f.cfg.Width, f.cfg.Height),
+	}, fzfArgs(f.cfg, opts)...)
+	cmd := exec.CommandContext(ctx, fzfTmux, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
+
+	out, err := cmd.Output()
+	if err != nil {
+		return Result{}, pickerError(ctx, "fzf-tmux", err)
+	}
+	return parseExpectOutput(string(out)), nil
+}
+
+// Fzf runs plain fzf inside a tmux popup
+type Fzf struct {
+	cfg Config
+}
+
+func (f *Fzf) Actions() bool {
+	return true
+}
+
+func (f *Fzf) Pick(ctx context.Context, items []string, opts Options) (Result, error) {
+	// NOTE: resolve binaries here since popups get the tmux server's environment, not flow's
+	fzf, err := exec.LookPath("fzf")
+	if err != nil {
+		return Result{}, errors.New("couldn't find fzf in the PATH")
+	}
+	return pickInPopup(ctx, f.cfg, "fzf", append([]string{fzf}, fzfArgs(f.cfg, opts)...), items)
+}
+
+// Skim runs skim (sk) inside a tmux popup
+type Skim struct {
+	cfg Config
+}
+
+func (s *Skim) Actions() bool {
+	return true
+}
+
+func (s *Skim) Pick(ctx context.Context, items []string, opts Options) (Result, error) {
+	sk, err := exec.LookPath("sk")
+	if err != nil {
+		return Result{}, errors.New("couldn't find sk in the PATH")
+	}
+
+	args := []string{
+		sk,
+		"--reverse", // display from top; overrides user skim config
+		"--ansi",
+		"--prompt",
+		opts.Prompt,
+		"--header",
+		opts.Header,
+		"--expect",
+		expectKeys(),
+	}
+	if opts.Preview != "" {
+		args = append(args,
+			"--preview",
+			opts.Preview,
+			"--preview-window",
+			fmt.Sprintf("%s:%d%%", opts.PreviewPos, opts.PreviewSize),
+		)
+	}
+	return pickInPopup(ctx, s.cfg, "sk", args, items)
+}
+
+// fzfArgs returns the fzf arguments shared by fzf-tmux and fzf
+func fzfArgs(cfg Config, opts Options) []string {
+	args := []string{
+		"--layout",
+		"reverse",    // display from top; overrides user fzf config
+		"--no-multi", // disable multi-select
+		"--prompt",
+		opts.Prompt,
+		"--header",
+		opts.Header,
+		"--expect",
+		expectKeys(),
+		"--border",
+		cfg.Border,
+		"--no-separator",
+	}
+	if opts.Preview != "" {
+		args = append(args,
+			"--preview",
+			opts.Preview,
+			"--preview-label",
+			opts.PreviewLabel,
+			"--preview-window",
+			fmt.Sprintf("%s,%d%%,border-%s", opts.PreviewPos, opts.PreviewSize, cfg.PreviewBorder),
+		)
+	}
+	return args
+}
+
+// pickInPopup runs an fzf-like picker in a tmux popup, passing items and the
+// output through temp files since the popup's stdio is its terminal
+func pickInPopup(ctx context.Context, cfg Config, name string, args []string, items []string) (Result, error) {
+	dir, err := os.MkdirTemp("", "flow-pick-")
+	if err != nil {
+		return Result{}, fmt.Errorf("couldn't create picker temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	itemsFile := filepath.Join(dir, "items")
+	outputFile := filepath.Join(dir, "output")
+	if err := os.WriteFile(itemsFile, []byte(strings.Join(items, "\n")), 0o600); err != nil {
+		return Result{}, fmt.Errorf("couldn't write picker items: %w", err)
+	}
+
+	command := fmt.Sprintf("%s < %s > %s", shell.Join(args), shell.Quote(itemsFile), shell.Quote(outputFile))
+	if err := displayPopup(ctx, cfg, command); err != nil {
+		return Result{}, pickerError(ctx, name, err)
+	}
+
+	out, err := os.ReadFile(outputFile)
+	if err != nil {
+		return Result{}, fmt.Errorf("couldn't read picker result: %w", err)
+	}
+	return parseExpectOutput(string(out)), nil
+}
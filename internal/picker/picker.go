@@ -0,0 +1,124 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Action is what the user asked for when leaving the picker
+type Action int
+
+const (
+	ActionSelect   Action = iota // <enter>: use the selection
+	ActionDirs                   // <tab>: list common dirs
+	ActionSessions               // <shift-tab>: list sessions
+	ActionKill                   // <ctrl-k>: kill the selected session
+)
+
+var actionNames = map[Action]string{
+	ActionSelect:   "select",
+	ActionDirs:     "dirs",
+	ActionSessions: "sessions",
+	ActionKill:     "kill",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// ParseAction is the inverse of Action.String
+func ParseAction(s string) (Action, error) {
+	for action, name := range actionNames {
+		if name == s {
+			return action, nil
+		}
+	}
+	return ActionSelect, errors.New("unknown picker action: " + s)
+}
+
+// ErrCancelled is returned when the user exits the picker without choosing anything
+var ErrCancelled = errors.New("picker cancelled")
+
+// Picker shows a list of items and reports the one the user chose and what
+// they want to do with it
+type Picker interface {
+	Pick(ctx context.Context, items []string, opts Options) (Result, error)
+	// Actions reports whether the picker can return actions other than ActionSelect
+	Actions() bool
+}
+
+type Options struct {
+	Prompt       string // shown before the query
+	Header       string // shown under the prompt; may contain ANSI escapes
+	Preview      string // shell command previewing the highlighted item; {} is replaced by the item
+	PreviewLabel string // title of the preview window
+	PreviewPos   string // "right", "left", "up" or "down"
+	PreviewSize  int    // percent of the screen used by the preview
+}
+
+type Result struct {
+	Action    Action
+	Selection string
+}
+
+// Config holds the settings shared by the picker backends
+type Config struct {
+	Width         string   // popup width, e.g. "80%"
+	Height        string   // popup height, e.g. "60%"
+	Border        string   // fzf border style
+	PreviewBorder string   // fzf preview window border style
+	DmenuCmd      []string // command and args of the dmenu-style picker
+	FlowCmd       []string // command that runs `flow pick` for the builtin picker
+}
+
+// New returns the picker backend with the given name
+func New(name string, cfg Config) (Picker, error) {
+	switch name {
+	case "fzf-tmux":
+		return &FzfTmux{cfg: cfg}, nil
+	case "fzf":
+		return &Fzf{cfg: cfg}, nil
+	case "skim":
+		return &Skim{cfg: cfg}, nil
+	case "dmenu":
+		if len(cfg.DmenuCmd) == 0 {
+			return nil, errors.New("dmenu picker needs a command")
+		}
+		return &Dmenu{cfg: cfg}, nil
+	case "builtin":
+		return &Builtin{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown picker %q: expected one of fzf-tmux, fzf, skim, dmenu or builtin", name)
+	}
+}
+
+// keys maps the keys pickers bind to actions other than ActionSelect
+var keys = map[string]Action{
+	"tab":       ActionDirs,
+	"shift-tab": ActionSessions,
+	"ctrl-k":    ActionKill,
+}
+
+// expectKeys returns the keys for fzf's --expect
+func expectKeys() string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+// parseExpectOutput parses the output of fzf-like pickers run with --expect:
+// the key pressed (empty for enter) and then the selection
+func parseExpectOutput(out string) Result {
+	key, selection, _ := strings.Cut(out, "\n")
+	result := Result{Action: ActionSelect, Selection: strings.TrimSpace(selection)}
+	if action, ok := keys[strings.TrimSpace(key)]; ok {
+		result.Action = action
+	}
+	return result
+}
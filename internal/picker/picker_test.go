@@ -0,0 +1,97 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"fzf-tmux", "fzf", "skim", "builtin"} {
+		if _, err := New(name, Config{}); err != nil {
+			t.Errorf("New(%q): unexpected error: %v", name, err)
+		}
+	}
+	if _, err := New("dmenu", Config{}); err == nil {
+		t.Error("Expected error for dmenu picker without a command")
+	}
+	if _, err := New("peco", Config{}); err == nil {
+		t.Error("Expected error for unknown picker")
+	}
+}
+
+func TestParseExpectOutput(t *testing.T) {
+	cases := []struct {
+		out  string
+		want Result
+	}{
+		{"\nmy-session\n", Result{ActionSelect, "my-session"}},
+		{"tab\n/home/user/code\n", Result{ActionDirs, "/home/user/code"}},
+		{"shift-tab\n\n", Result{ActionSessions, ""}},
+		{"ctrl-k\nold-session\n", Result{ActionKill, "old-session"}},
+	}
+	for _, c := range cases {
+		if got := parseExpectOutput(c.out); got != c.want {
+			t.Errorf("parseExpectOutput(%q): expected %v but got %v", c.out, c.want, got)
+		}
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	for _, result := range []Result{{ActionSelect, "/code/api"}, {ActionKill, "old"}, {ActionDirs, ""}} {
+		got, err := ParseResult(FormatResult(result))
+		if err != nil || got != result {
+			t.Errorf("Expected %v to round trip but got %v (%v)", result, got, err)
+		}
+	}
+	if _, err := ParseResult("explode\nx"); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
+
+func TestDmenu(t *testing.T) {
+	p, err := New("dmenu", Config{DmenuCmd: []string{"sed", "-n", "2p"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Actions() {
+		t.Error("Expected dmenu picker to not support actions")
+	}
+
+	result, err := p.Pick(context.Background(), []string{"first", "second", "third"}, Options{})
+	if err != nil || result != (Result{ActionSelect, "second"}) {
+		t.Errorf("Expected second item to be selected but got %v (%v)", result, err)
+	}
+
+	if _, err := p.Pick(context.Background(), []string{"only"}, Options{}); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected empty output to cancel but got %v", err)
+	}
+
+	p, _ = New("dmenu", Config{DmenuCmd: []string{"false"}})
+	if _, err := p.Pick(context.Background(), []string{"item"}, Options{}); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected exit code 1 to cancel but got %v", err)
+	}
+}
+
+func TestFzfArgs(t *testing.T) {
+	args := fzfArgs(Config{Border: "rounded", PreviewBorder: "sharp"}, Options{
+		Prompt:      "Sessions: ",
+		Preview:     "ls {}",
+		PreviewPos:  "right",
+		PreviewSize: 60,
+	})
+	i := slices.Index(args, "--preview-window")
+	if i < 0 || args[i+1] != "right,60%,border-sharp" {
+		t.Errorf("Expected preview window right,60%%,border-sharp in %v", args)
+	}
+	i = slices.Index(args, "--expect")
+	if i < 0 || args[i+1] != "ctrl-k,shift-tab,tab" {
+		t.Errorf("Expected action keys to be expected in %v", args)
+	}
+
+	args = fzfArgs(Config{}, Options{})
+	if slices.Contains(args, "--preview") {
+		t.Errorf("Expected no preview args without a preview command in %v", args)
+	}
+}
@@ -0,0 +1,62 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// exitCancelled is the exit code fzf-like pickers use for <esc> and <ctrl-c>
+const exitCancelled = 130
+
+// exitNoMatch is the exit code fzf-like pickers use when nothing matched the query
+const exitNoMatch = 1
+
+// displayPopup runs a shell command in a tmux popup on the current client and
+// waits for it to exit; requires tmux 3.3+ for the border style
+func displayPopup(ctx context.Context, cfg Config, command string) error {
+	args := []string{
+		"display-popup",
+		"-E", // close popup when the command exits
+		"-w",
+		cfg.Width,
+		"-h",
+		cfg.Height,
+		"-b",
+		popupBorder(cfg.Border),
+		command,
+	}
+	_, _, err := tmux.Cmd(ctx, args)
+	return err
+}
+
+// popupBorder maps fzf border styles to the closest tmux popup border lines
+func popupBorder(fzfBorder string) string {
+	switch fzfBorder {
+	case "rounded", "double", "none":
+		return fzfBorder
+	case "bold":
+		return "heavy"
+	default:
+		return "single"
+	}
+}
+
+// pickerError converts the exit status of a picker into ErrCancelled when the user
+// backed out, nothing matched or ctx was cancelled
+func pickerError(ctx context.Context, name string, err error) error {
+	if ctx.Err() != nil {
+		return ErrCancelled
+	}
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		switch exitError.ExitCode() {
+		case exitCancelled, exitNoMatch:
+			return ErrCancelled
+		}
+	}
+	return fmt.Errorf("error running %s: %w", name, err)
+}
@@ -0,0 +1,450 @@
+package picker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/winter-again/flow/internal/shell"
+)
+
+const previewTimeout = 2 * time.Second
+
+type key struct {
+	name string // named key, e.g. "up" or "ctrl-k"; empty for printable runes
+	r    rune
+}
+
+type previewResult struct {
+	item   string
+	output string
+}
+
+type tui struct {
+	items   []string
+	opts    Options
+	query   []rune
+	matches []Match
+	cursor  int
+	offset  int
+	width   int
+	height  int
+	preview previewResult
+}
+
+// Run shows the items in an interactive fuzzy finder on the terminal tty until the
+// user selects an item, triggers another action or cancels with <esc>, <ctrl-c> or ctx
+func Run(ctx context.Context, tty *os.File, items []string, opts Options) (Result, error) {
+	fd := int(tty.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return Result{}, fmt.Errorf("couldn't put terminal in raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	// NOTE: alternate screen so the popup's previous contents come back on exit
+	tty.WriteString("\x1b[?1049h\x1b[?25l")
+	defer tty.WriteString("\x1b[?25h\x1b[?1049l")
+
+	t := &tui{items: items, opts: opts}
+	t.resize(fd)
+	t.filter()
+
+	keys := make(chan key)
+	go readKeys(tty, keys)
+
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+	defer signal.Stop(resized)
+
+	// NOTE: stale previews are left to finish or time out; their output is discarded
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	previews := make(chan previewResult)
+	requested := ""
+
+	for {
+		if item, ok := t.current(); ok && opts.Preview != "" && item != requested {
+			requested = item
+			go runPreview(ctx, opts.Preview, item, previews)
+		}
+		tty.Write(t.render())
+
+		select {
+		case <-ctx.Done():
+			return Result{}, ErrCancelled
+		case <-resized:
+			t.resize(fd)
+		case p := <-previews:
+			if p.item == requested {
+				t.preview = p
+			}
+		case k, ok := <-keys:
+			if !ok {
+				return Result{}, ErrCancelled
+			}
+			if result, done := t.handle(k); done {
+				if result == nil {
+					return Result{}, ErrCancelled
+				}
+				return *result, nil
+			}
+		}
+	}
+}
+
+// handle applies a key press, returning whether the picker is done and its result.
+// A nil result means the user cancelled
+func (t *tui) handle(k key) (*Result, bool) {
+	if k.name == "enter" {
+		item, ok := t.current()
+		if !ok {
+			return nil, false
+		}
+		return &Result{Action: ActionSelect, Selection: item}, true
+	}
+	if action, ok := keys[k.name]; ok {
+		item, _ := t.current()
+		return &Result{Action: action, Selection: item}, true
+	}
+
+	switch k.name {
+	case "esc", "ctrl-c":
+		return nil, true
+	case "up", "ctrl-p":
+		t.move(-1)
+	case "down", "ctrl-n", "ctrl-j":
+		t.move(1)
+	case "pgup":
+		t.move(-t.listHeight())
+	case "pgdown":
+		t.move(t.listHeight())
+	case "backspace":
+		if len(t.query) > 0 {
+			t.query = t.query[:len(t.query)-1]
+			t.filter()
+		}
+	case "ctrl-u":
+		t.query = t.query[:0]
+		t.filter()
+	case "ctrl-w":
+		q := strings.TrimRight(string(t.query), " ")
+		if i := strings.LastIndex(q, " "); i >= 0 {
+			q = q[:i+1]
+		} else {
+			q = ""
+		}
+		t.query = []rune(q)
+		t.filter()
+	case "":
+		t.query = append(t.query, k.r)
+		t.filter()
+	}
+	return nil, false
+}
+
+func (t *tui) filter() {
+	t.matches = Filter(string(t.query), t.items)
+	t.cursor = 0
+	t.offset = 0
+}
+
+func (t *tui) current() (string, bool) {
+	if len(t.matches) == 0 {
+		return "", false
+	}
+	return t.matches[t.cursor].Item, true
+}
+
+func (t *tui) move(delta int) {
+	t.cursor = max(0, min(len(t.matches)-1, t.cursor+delta))
+}
+
+func (t *tui) resize(fd int) {
+	w, h, err := term.GetSize(fd)
+	if err != nil {
+		w, h = 80, 24
+	}
+	t.width, t.height = w, h
+}
+
+type rect struct {
+	row, col, width, height int
+}
+
+// layout splits the screen into the list and preview areas
+func (t *tui) layout() (rect, rect) {
+	screen := rect{0, 0, t.width, t.height}
+	if t.opts.Preview == "" {
+		return screen, rect{}
+	}
+
+	size := t.opts.PreviewSize
+	if size <= 0 || size >= 100 {
+		size = 50
+	}
+	switch t.opts.PreviewPos {
+	case "up", "down":
+		ph := t.height * size / 100
+		if t.height-ph-1 < 4 {
+			return screen, rect{}
+		}
+		list := rect{0, 0, t.width, t.height - ph - 1}
+		preview := rect{list.height + 1, 0, t.width, ph}
+		if t.opts.PreviewPos == "up" {
+			preview.row = 0
+			list.row = ph + 1
+		}
+		return list, preview
+	default:
+		pw := t.width * size / 100
+		if t.width-pw-1 < 20 {
+			return screen, rect{}
+		}
+		list := rect{0, 0, t.width - pw - 1, t.height}
+		preview := rect{0, list.width + 1, pw, t.height}
+		if t.opts.PreviewPos == "left" {
+			preview.col = 0
+			list.col = pw + 1
+		}
+		return list, preview
+	}
+}
+
+// listHeight is the number of rows available for items below the prompt, header and counter
+func (t *tui) listHeight() int {
+	list, _ := t.layout()
+	rows := list.height - 2
+	if t.opts.Header != "" {
+		rows--
+	}
+	return max(1, rows)
+}
+
+func (t *tui) render() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H\x1b[2J")
+
+	list, preview := t.layout()
+	row := list.row
+	line := func(s string) {
+		fmt.Fprintf(&buf, "\x1b[%d;%dH%s\x1b[m", row+1, list.col+1, truncateANSI(s, list.width))
+		row++
+	}
+
+	line(fmt.Sprintf("\x1b[1;34m%s\x1b[m%s\x1b[7m \x1b[m", t.opts.Prompt, string(t.query)))
+	if t.opts.Header != "" {
+		line(t.opts.Header)
+	}
+	line(fmt.Sprintf("\x1b[2m  %d/%d\x1b[m", len(t.matches), len(t.items)))
+
+	rows := t.listHeight()
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	} else if t.cursor >= t.offset+rows {
+		t.offset = t.cursor - rows + 1
+	}
+	for i := t.offset; i < len(t.matches) && i < t.offset+rows; i++ {
+		if i == t.cursor {
+			line("\x1b[1;31m>\x1b[m \x1b[1m" + highlight(t.matches[i], "\x1b[1m"))
+		} else {
+			line("  " + highlight(t.matches[i], ""))
+		}
+	}
+
+	if preview.width > 0 {
+		t.renderPreview(&buf, list, preview)
+	}
+	return buf.Bytes()
+}
+
+func (t *tui) renderPreview(buf *bytes.Buffer, list rect, preview rect) {
+	// border between list and preview
+	if preview.col != list.col {
+		col := list.col + list.width + 1
+		if preview.col < list.col {
+			col = preview.col + preview.width + 1
+		}
+		for r := 0; r < t.height; r++ {
+			fmt.Fprintf(buf, "\x1b[%d;%dH\x1b[2m│\x1b[m", r+1, col)
+		}
+	} else {
+		r := list.row + list.height + 1
+		if preview.row < list.row {
+			r = preview.row + preview.height + 1
+		}
+		fmt.Fprintf(buf, "\x1b[%d;1H\x1b[2m%s\x1b[m", r, strings.Repeat("─", t.width))
+	}
+
+	item, _ := t.current()
+	if t.preview.item != item {
+		return
+	}
+	lines := strings.Split(strings.ReplaceAll(t.preview.output, "\t", "    "), "\n")
+	for i := 0; i < len(lines) && i < preview.height; i++ {
+		fmt.Fprintf(buf, "\x1b[%d;%dH%s\x1b[m", preview.row+i+1, preview.col+1, truncateANSI(lines[i], preview.width))
+	}
+}
+
+// highlight emphasizes the matched runes of an item, restoring style afterwards
+func highlight(m Match, style string) string {
+	if len(m.Positions) == 0 {
+		return m.Item
+	}
+	var b strings.Builder
+	p := 0
+	for i, r := range []rune(m.Item) {
+		if p < len(m.Positions) && m.Positions[p] == i {
+			b.WriteString("\x1b[1;32m")
+			b.WriteRune(r)
+			b.WriteString("\x1b[m" + style)
+			p++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// truncateANSI cuts s to width visible cells, passing escape sequences through
+func truncateANSI(s string, width int) string {
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			j := escapeEnd(s, i)
+			b.WriteString(s[i:j])
+			i = j
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == '\r' || r < ' ' {
+			i += size
+			continue
+		}
+		if visible >= width {
+			break
+		}
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	return b.String()
+}
+
+// escapeEnd returns the index just past the escape sequence starting at s[i]
+func escapeEnd(s string, i int) int {
+	j := i + 1
+	if j >= len(s) {
+		return j
+	}
+	switch s[j] {
+	case '[': // CSI: parameters then a final byte in @ to ~
+		j++
+		for j < len(s) && (s[j] < '@' || s[j] > '~') {
+			j++
+		}
+		return min(j+1, len(s))
+	case 'O': // SS3: a single final byte
+		return min(j+2, len(s))
+	case ']': // OSC: terminated by BEL or ST
+		for j < len(s) && s[j] != '\a' && !(s[j] == '\\' && s[j-1] == '\x1b') {
+			j++
+		}
+		return min(j+1, len(s))
+	default:
+		return j + 1
+	}
+}
+
+// runPreview runs the preview command for item and sends back its output
+func runPreview(ctx context.Context, command string, item string, out chan<- previewResult) {
+	ctx, cancel := context.WithTimeout(ctx, previewTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", strings.ReplaceAll(command, "{}", shell.Quote(item)))
+	output, _ := cmd.CombinedOutput()
+	select {
+	case out <- previewResult{item: item, output: string(output)}:
+	case <-ctx.Done():
+	}
+}
+
+// readKeys decodes key presses from the tty until it's closed
+func readKeys(tty *os.File, keys chan<- key) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+var escapeKeys = map[string]string{
+	"\x1b[A":  "up",
+	"\x1bOA":  "up",
+	"\x1b[B":  "down",
+	"\x1bOB":  "down",
+	"\x1b[Z":  "shift-tab",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+}
+
+var controlKeys = map[byte]string{
+	0x03: "ctrl-c",
+	0x08: "backspace",
+	0x09: "tab",
+	0x0a: "ctrl-j",
+	0x0b: "ctrl-k",
+	0x0d: "enter",
+	0x0e: "ctrl-n",
+	0x10: "ctrl-p",
+	0x15: "ctrl-u",
+	0x17: "ctrl-w",
+	0x7f: "backspace",
+}
+
+// parseKeys decodes the bytes of one read from the terminal into key presses.
+// Unknown escape sequences and control bytes are dropped
+func parseKeys(b []byte) []key {
+	var keys []key
+	for i := 0; i < len(b); {
+		switch {
+		case b[i] == 0x1b:
+			if i+1 == len(b) {
+				keys = append(keys, key{name: "esc"})
+				i++
+				continue
+			}
+			j := escapeEnd(string(b), i)
+			if name, ok := escapeKeys[string(b[i:j])]; ok {
+				keys = append(keys, key{name: name})
+			}
+			i = j
+		case b[i] < 0x20 || b[i] == 0x7f:
+			if name, ok := controlKeys[b[i]]; ok {
+				keys = append(keys, key{name: name})
+			}
+			i++
+		default:
+			r, size := utf8.DecodeRune(b[i:])
+			keys = append(keys, key{r: r})
+			i += size
+		}
+	}
+	return keys
+}
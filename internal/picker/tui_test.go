@@ -0,0 +1,73 @@
+package picker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("ab\x1b[A\x1bOB\x1b[Z\t\r\x0b\x7fé\x1b[1;5C\x03"))
+	want := []key{
+		{r: 'a'},
+		{r: 'b'},
+		{name: "up"},
+		{name: "down"},
+		{name: "shift-tab"},
+		{name: "tab"},
+		{name: "enter"},
+		{name: "ctrl-k"},
+		{name: "backspace"},
+		{r: 'é'},
+		{name: "ctrl-c"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected keys %v but got %v", want, got)
+	}
+
+	if got := parseKeys([]byte("\x1b")); !slices.Equal(got, []key{{name: "esc"}}) {
+		t.Errorf("Expected lone escape to be esc but got %v", got)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	tu := &tui{items: []string{"alpha", "beta", "gamma"}, width: 80, height: 24}
+	tu.filter()
+
+	tu.handle(key{name: "down"})
+	if result, done := tu.handle(key{name: "tab"}); !done || result.Action != ActionDirs || result.Selection != "beta" {
+		t.Errorf("Expected tab to return dirs action for beta but got %v", result)
+	}
+
+	for _, r := range "mm" {
+		tu.handle(key{r: r})
+	}
+	if result, done := tu.handle(key{name: "enter"}); !done || result.Action != ActionSelect || result.Selection != "gamma" {
+		t.Errorf("Expected enter to select gamma but got %v", result)
+	}
+
+	tu.handle(key{r: 'z'})
+	if _, done := tu.handle(key{name: "enter"}); done {
+		t.Error("Expected enter without matches to be ignored")
+	}
+	if result, done := tu.handle(key{name: "esc"}); !done || result != nil {
+		t.Errorf("Expected esc to cancel but got %v", result)
+	}
+}
+
+func TestTruncateANSI(t *testing.T) {
+	cases := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"hello world", 5, "hello"},
+		{"\x1b[1;32mhello\x1b[m world", 3, "\x1b[1;32mhel"},
+		{"tab\there", 10, "tabhere"},
+		{"short", 10, "short"},
+	}
+	for _, c := range cases {
+		if got := truncateANSI(c.in, c.width); got != c.want {
+			t.Errorf("truncateANSI(%q, %d): expected %q but got %q", c.in, c.width, c.want, got)
+		}
+	}
+}
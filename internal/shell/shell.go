@@ -0,0 +1,17 @@
+package shell
+
+import "strings"
+
+// Quote wraps s in single quotes for sh
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes each arg for sh and joins them into a command line
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,17 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	args := []string{"it's", "$HOME", "a b", ""}
+	out, err := exec.Command("sh", "-c", `printf '%s|' `+Join(args)).Output()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := string(out), "it's|$HOME|a b||"; got != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+}
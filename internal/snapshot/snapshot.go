@@ -0,0 +1,248 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/winter-again/flow/internal/layout"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// Version is the snapshot file format version; bump it on incompatible changes
+const Version = 1
+
+const (
+	filePrefix = "snapshot-"
+	fileExt    = ".json"
+	timeFormat = "20060102T150405.000"
+)
+
+var ErrNoSnapshots = errors.New("no snapshots found")
+
+type Snapshot struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Sessions []Session `json:"sessions"`
+}
+
+type Session struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Windows []Window `json:"windows"`
+}
+
+type Window struct {
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Layout string `json:"layout"`
+	Active bool   `json:"active"`
+	Panes  []Pane `json:"panes"`
+}
+
+type Pane struct {
+	Index   int    `json:"index"`
+	Path    string `json:"path"`
+	Command string `json:"command"`
+	Active  bool   `json:"active"`
+}
+
+// Take captures the sessions, windows and panes of the server
+func Take(ctx context.Context, server *tmux.Server) (*Snapshot, error) {
+	sessions, err := server.GetSessions(ctx)
+	if err != nil {
+		return &Snapshot{}, err
+	}
+	windows, err := server.GetWindows(ctx, nil)
+	if err != nil {
+		return &Snapshot{}, err
+	}
+	panes, err := server.GetPanes(ctx, nil)
+	if err != nil {
+		return &Snapshot{}, err
+	}
+
+	panesByWindow := make(map[string][]Pane)
+	for _, p := range panes {
+		panesByWindow[p.WindowId] = append(panesByWindow[p.WindowId], Pane{
+			Index:   p.Index,
+			Path:    p.CurrentPath,
+			Command: p.CurrentCommand,
+			Active:  p.Active,
+		})
+	}
+
+	windowsBySession := make(map[string][]Window)
+	for _, w := range windows {
+		windowsBySession[w.SessionId] = append(windowsBySession[w.SessionId], Window{
+			Index:  w.Index,
+			Name:   w.Name,
+			Layout: w.Layout,
+			Active: w.Active,
+			Panes:  panesByWindow[w.Id],
+		})
+	}
+
+	snap := &Snapshot{
+		Version: Version,
+		Created: time.Now(),
+	}
+	for _, s := range sessions {
+		snap.Sessions = append(snap.Sessions, Session{
+			Name:    s.Name,
+			Path:    s.Path,
+			Windows: windowsBySession[s.Id],
+		})
+	}
+	return snap, nil
+}
+
+// Save writes the snapshot to a new timestamped file in dir and removes the
+// oldest snapshots so that at most keep remain. A keep below 1 keeps everything
+func Save(dir string, snap *Snapshot, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("couldn't create snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("couldn't encode snapshot: %w", err)
+	}
+
+	path := filepath.Join(dir, filePrefix+snap.Created.Format(timeFormat)+fileExt)
+	// NOTE: write then rename so that a crash never leaves a truncated snapshot behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", fmt.Errorf("couldn't write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("couldn't write snapshot: %w", err)
+	}
+
+	if keep > 0 {
+		files, err := List(dir)
+		if err != nil {
+			return path, err
+		}
+		for len(files) > keep {
+			if err := os.Remove(files[0]); err != nil {
+				return path, fmt.Errorf("couldn't remove old snapshot: %w", err)
+			}
+			files = files[1:]
+		}
+	}
+	return path, nil
+}
+
+// List returns the snapshot files in dir from oldest to newest
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+		return []string{}, fmt.Errorf("couldn't read snapshot directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileExt) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	// NOTE: timestamps in the file names sort chronologically
+	slices.Sort(files)
+	return files, nil
+}
+
+// Latest returns the newest snapshot file in dir
+func Latest(dir string) (string, error) {
+	files, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", ErrNoSnapshots
+	}
+	return files[len(files)-1], nil
+}
+
+// Load reads a snapshot file
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &Snapshot{}, fmt.Errorf("couldn't read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return &Snapshot{}, fmt.Errorf("couldn't decode snapshot %s: %w", path, err)
+	}
+	if snap.Version != Version {
+		return &Snapshot{}, fmt.Errorf("unsupported snapshot version %d in %s (want %d)", snap.Version, path, Version)
+	}
+	return &snap, nil
+}
+
+// Restore recreates the snapshot's sessions in the server, optionally only those
+// named in sessionNames. Sessions that already exist are skipped. Commands that were
+// running in panes are only restarted if they're in restoreCmds. Returns the names of
+// the restored sessions
+func Restore(ctx context.Context, server *tmux.Server, snap *Snapshot, sessionNames []string, restoreCmds []string) ([]string, error) {
+	var restored []string
+	for _, s := range snap.Sessions {
+		if len(sessionNames) > 0 && !slices.Contains(sessionNames, s.Name) {
+			continue
+		}
+		if server.SessionExists(ctx, s.Name) {
+			continue
+		}
+
+		session, err := server.CreateSession(ctx, s.Name, s.Path)
+		if err != nil {
+			return restored, fmt.Errorf("couldn't restore session %s: %w", s.Name, err)
+		}
+		if err := layout.Apply(ctx, server, session, toProject(s, restoreCmds)); err != nil {
+			return restored, fmt.Errorf("couldn't restore windows of session %s: %w", s.Name, err)
+		}
+		restored = append(restored, s.Name)
+	}
+	return restored, nil
+}
+
+// toProject converts a saved session into a layout that rebuilds it
+func toProject(s Session, restoreCmds []string) *layout.Project {
+	windows := slices.Clone(s.Windows)
+	slices.SortFunc(windows, func(a, b Window) int { return a.Index - b.Index })
+
+	project := &layout.Project{}
+	for _, w := range windows {
+		panes := slices.Clone(w.Panes)
+		slices.SortFunc(panes, func(a, b Pane) int { return a.Index - b.Index })
+
+		lw := layout.Window{
+			Name:   w.Name,
+			Layout: w.Layout,
+			Focus:  w.Active,
+		}
+		for _, p := range panes {
+			lp := layout.Pane{
+				Dir:   p.Path,
+				Focus: p.Active,
+			}
+			if slices.Contains(restoreCmds, p.Command) {
+				lp.Cmd = p.Command
+			}
+			lw.Panes = append(lw.Panes, lp)
+		}
+		project.Windows = append(project.Windows, lw)
+	}
+	return project
+}
@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveRotation(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var paths []string
+	for i := range 4 {
+		snap := &Snapshot{Version: Version, Created: start.Add(time.Duration(i) * time.Minute)}
+		path, err := Save(dir, snap, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	files, err := List(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 2 || files[0] != paths[2] || files[1] != paths[3] {
+		t.Errorf("Expected only the 2 newest snapshots %v but got %v", paths[2:], files)
+	}
+
+	latest, err := Latest(dir)
+	if err != nil || latest != paths[3] {
+		t.Errorf("Expected latest snapshot %s but got %s (%v)", paths[3], latest, err)
+	}
+}
+
+func TestLatestEmpty(t *testing.T) {
+	if _, err := Latest(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrNoSnapshots) {
+		t.Errorf("Expected ErrNoSnapshots but got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	snap := &Snapshot{
+		Version: Version,
+		Created: time.Now(),
+		Sessions: []Session{
+			{Name: "api", Path: "/code/api", Windows: []Window{{Name: "editor", Panes: []Pane{{Path: "/code/api", Command: "nvim"}}}}},
+		},
+	}
+	path, err := Save(dir, snap, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(loaded.Sessions) != 1 || loaded.Sessions[0].Windows[0].Panes[0].Command != "nvim" {
+		t.Errorf("Expected loaded snapshot to match saved one but got %+v", *loaded)
+	}
+
+	future := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(future, []byte(`{"version": 99}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(future); err == nil {
+		t.Error("Expected error for unsupported snapshot version")
+	}
+}
+
+func TestToProject(t *testing.T) {
+	session := Session{
+		Name: "api",
+		Path: "/code/api",
+		Windows: []Window{
+			{Index: 2, Name: "logs", Active: true, Panes: []Pane{{Index: 0, Path: "/var/log", Command: "tail"}}},
+			{Index: 1, Name: "editor", Layout: "b25d,80x24,0,0,1", Panes: []Pane{
+				{Index: 1, Path: "/code/api/cmd", Command: "zsh", Active: true},
+				{Index: 0, Path: "/code/api", Command: "nvim"},
+			}},
+		},
+	}
+
+	project := toProject(session, []string{"nvim"})
+	if len(project.Windows) != 2 || project.Windows[0].Name != "editor" || project.Windows[1].Name != "logs" {
+		t.Fatalf("Expected windows ordered by index but got %+v", project.Windows)
+	}
+	editor := project.Windows[0]
+	if editor.Layout != "b25d,80x24,0,0,1" || editor.Focus {
+		t.Errorf("Unexpected editor window: %+v", editor)
+	}
+	if editor.Panes[0].Cmd != "nvim" || editor.Panes[1].Cmd != "" || !editor.Panes[1].Focus {
+		t.Errorf("Expected only allowed commands restored but got %+v", editor.Panes)
+	}
+	if !project.Windows[1].Focus || project.Windows[1].Panes[0].Cmd != "" {
+		t.Errorf("Unexpected logs window: %+v", project.Windows[1])
+	}
+}
@@ -0,0 +1,334 @@
+package tmux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrControlClosed is returned for commands run after a control client exits on its own
+var ErrControlClosed = errors.New("control client exited")
+
+// Control is a tmux client in control mode (see CONTROL MODE in tmux(1)). It keeps one
+// connection to the server open, so commands don't each spawn tmux, and reports what
+// changes in the server as notifications. It's a Runner, so a Server can use it
+type Control struct {
+	stdin io.WriteCloser
+	stop  func() // kills the client if it doesn't exit on its own
+
+	mu      sync.Mutex // guards writing commands along with pending, to keep them in the same order
+	pending []chan controlReply
+	closed  bool
+	err     error // why the client exited; set before closed
+
+	notifications chan Notification
+	done          chan struct{} // closed once the client has exited
+}
+
+// Notification is a line tmux sends a control client about a change in the server,
+// e.g. %sessions-changed or %window-add @1
+type Notification struct {
+	Name string   // name without the %, e.g. "session-renamed"
+	Args []string // e.g. the session ID and new name
+}
+
+// controlReply is the output of a command, between its %begin and its %end or %error
+type controlReply struct {
+	output []string
+	failed bool
+}
+
+// notificationArgs is the number of args of the notifications that end in a name,
+// since names may contain spaces
+var notificationArgs = map[string]int{
+	"session-renamed":        2,
+	"session-changed":        2,
+	"client-session-changed": 3,
+	"window-renamed":         2,
+}
+
+// Connect starts a control client attached to the server's most recently used
+// session. Close it when done
+func (server *Server) Connect(ctx context.Context) (*Control, error) {
+	tmux, err := exec.LookPath("tmux")
+	if err != nil {
+		return nil, ErrTmuxNotFound
+	}
+	// NOTE: attach-session starts a server if there isn't one, which then loads the
+	// user's config, so don't let it
+	if _, err := os.Stat(server.SocketPath); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoServer, server.SocketPath)
+	}
+
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"-C", // control mode
+		"attach-session",
+		"-f", // NOTE: the client doesn't need pane output and shouldn't resize windows
+		"no-output,ignore-size",
+	}
+	// NOTE: not CommandContext since the client outlives ctx; Close stops it
+	cmd := exec.Command(tmux, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, commandError(args, "", err)
+	}
+
+	wait := func() error {
+		err := cmd.Wait()
+		return commandError(args, stderr.String(), err)
+	}
+	stop := func() {
+		cmd.Process.Kill()
+		// NOTE: the server may hold on to the client's stdout, so stop reading it too
+		stdout.Close()
+	}
+	c, startup := newControl(stdin, stdout, wait, stop)
+
+	// NOTE: tmux replies to attach-session like any other command, which tells
+	// whether the client made it
+	if _, stderr, err := c.reply(ctx, startup); err != nil {
+		c.Close()
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			return nil, err
+		}
+		return nil, commandError(args, stderr, err)
+	}
+	return c, nil
+}
+
+// newControl starts reading the output of a control client from stdout; wait returns
+// why the client exited once stdout is done. Also returns where the reply to the
+// command the client was started with goes
+func newControl(stdin io.WriteCloser, stdout io.Reader, wait func() error, stop func()) (*Control, chan controlReply) {
+	startup := make(chan controlReply, 1)
+	c := &Control{
+		stdin:         stdin,
+		stop:          stop,
+		pending:       []chan controlReply{startup},
+		notifications: make(chan Notification, 64),
+		done:          make(chan struct{}),
+	}
+	go c.read(stdout, wait)
+	return c, startup
+}
+
+// Run runs a tmux command through the client; returns stdout and stderr like ExecRunner.
+// Global flags like -S are dropped since the client is already connected
+func (c *Control) Run(ctx context.Context, args []string) (string, string, error) {
+	line, err := commandLine(commandArgs(args))
+	if err != nil {
+		return "", "", err
+	}
+
+	reply := make(chan controlReply, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return "", "", c.err
+	}
+	c.pending = append(c.pending, reply)
+	_, err = io.WriteString(c.stdin, line+"\n")
+	c.mu.Unlock()
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't send command to control client: %w", err)
+	}
+	return c.reply(ctx, reply)
+}
+
+// reply waits for the reply to a command; a failed command's output is its stderr
+func (c *Control) reply(ctx context.Context, reply chan controlReply) (string, string, error) {
+	select {
+	case r, ok := <-reply:
+		if !ok {
+			return "", "", c.err
+		}
+		var out string
+		if len(r.output) > 0 {
+			out = strings.Join(r.output, "\n") + "\n"
+		}
+		if r.failed {
+			return "", out, exitStatus(1)
+		}
+		return out, "", nil
+	case <-ctx.Done():
+		// NOTE: the reply still comes later but goes unread
+		return "", "", ctx.Err()
+	}
+}
+
+// Notifications returns the changes in the server as tmux reports them. Notifications
+// are dropped while the channel is full, and it's closed once the client exits
+func (c *Control) Notifications() <-chan Notification {
+	return c.notifications
+}
+
+// Err returns why the client exited, or nil while it's running
+func (c *Control) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close detaches the client and waits for it to exit
+func (c *Control) Close() error {
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(waitDelay):
+		c.stop()
+		<-c.done
+	}
+	if errors.Is(c.err, ErrControlClosed) {
+		return nil
+	}
+	return c.err
+}
+
+// read handles the output of the client until it exits, sending replies to the
+// pending commands in order and everything else to the notifications
+func (c *Control) read(stdout io.Reader, wait func() error) {
+	scanner := bufio.NewScanner(stdout)
+	// NOTE: lines like layouts or captured panes can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var block *controlReply
+	var guard string // time and number of the current block, which its end repeats
+	exitReason := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if block != nil {
+			name, rest, _ := strings.Cut(line, " ")
+			if (name == "%end" || name == "%error") && strings.HasPrefix(rest, guard) {
+				block.failed = name == "%error"
+				c.deliver(*block)
+				block = nil
+				continue
+			}
+			block.output = append(block.output, line)
+			continue
+		}
+
+		name, rest, _ := strings.Cut(line, " ")
+		switch {
+		case name == "%begin":
+			fields := strings.Fields(rest)
+			if len(fields) < 2 {
+				continue
+			}
+			guard = fields[0] + " " + fields[1] + " "
+			block = &controlReply{}
+		case name == "%exit":
+			exitReason = rest
+		case strings.HasPrefix(name, "%"):
+			c.notify(parseNotification(line))
+		}
+	}
+
+	err := wait()
+	if err == nil {
+		err = scanner.Err()
+	}
+	if err == nil {
+		err = ErrControlClosed
+		if exitReason != "" {
+			err = fmt.Errorf("%w: %s", ErrControlClosed, exitReason)
+		}
+	}
+
+	c.mu.Lock()
+	c.err = err
+	c.closed = true
+	for _, reply := range c.pending {
+		close(reply)
+	}
+	c.pending = nil
+	c.mu.Unlock()
+	close(c.notifications)
+	close(c.done)
+}
+
+// deliver sends a reply to the command waiting longest
+func (c *Control) deliver(reply controlReply) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) == 0 {
+		return
+	}
+	c.pending[0] <- reply
+	c.pending = c.pending[1:]
+}
+
+// notify sends a notification unless nobody is keeping up with them
+func (c *Control) notify(n Notification) {
+	select {
+	case c.notifications <- n:
+	default:
+	}
+}
+
+// parseNotification parses a notification line like "%window-renamed @1 name"
+func parseNotification(line string) Notification {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(line, "%"), " ")
+	n := Notification{Name: name}
+	if rest == "" {
+		return n
+	}
+	if count, ok := notificationArgs[name]; ok {
+		n.Args = strings.SplitN(rest, " ", count)
+	} else {
+		n.Args = strings.Fields(rest)
+	}
+	return n
+}
+
+// commandLine quotes args into a line for tmux's command parser
+func commandLine(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("no tmux command to run")
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		// NOTE: each command is a single line
+		if strings.ContainsAny(arg, "\r\n") {
+			return "", fmt.Errorf("control client can't send args with newlines: %q", arg)
+		}
+		// NOTE: single quotes keep tmux from expanding ~ and $VARS or splitting on ;
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// exitStatus is the error of a command that failed in a control client, which
+// reports no exit code of its own
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (e exitStatus) ExitCode() int {
+	return int(e)
+}
@@ -0,0 +1,132 @@
+package tmux
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+// fakeControl connects a Control to pipes the test plays tmux on: commands come out
+// of lines and the test writes tmux's output to out
+func fakeControl(t *testing.T) (*Control, chan controlReply, *bufio.Scanner, *io.PipeWriter) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c, startup := newControl(inW, outR, func() error { return nil }, func() { outW.Close() })
+	t.Cleanup(func() {
+		outW.Close()
+		inR.Close()
+	})
+	return c, startup, bufio.NewScanner(inR), outW
+}
+
+func TestControl(t *testing.T) {
+	ctx := context.Background()
+	c, startup, lines, out := fakeControl(t)
+
+	io.WriteString(out, "%begin 1 10 0\n%end 1 10 0\n%session-changed $1 my session\n")
+	if _, _, err := c.reply(ctx, startup); err != nil {
+		t.Fatalf("Unexpected startup error: %v", err)
+	}
+
+	type result struct {
+		stdout, stderr string
+		err            error
+	}
+	results := make(chan result)
+	run := func(args ...string) {
+		go func() {
+			stdout, stderr, err := c.Run(ctx, args)
+			results <- result{stdout, stderr, err}
+		}()
+	}
+
+	run("-S", "/tmp/tmux-1000/work", "display-message", "-p", "it's #{session_name}")
+	if !lines.Scan() {
+		t.Fatal("Expected a command line")
+	}
+	if want := `'display-message' '-p' 'it'\''s #{session_name}'`; lines.Text() != want {
+		t.Errorf("Expected command line %s but got %s", want, lines.Text())
+	}
+	// NOTE: output that looks like the end of a block but isn't this block's
+	io.WriteString(out, "%begin 2 11 1\nit's my session\n%end 2 99 1\n%end 2 11 1\n")
+	if r := <-results; r.err != nil || r.stdout != "it's my session\n%end 2 99 1\n" {
+		t.Errorf("Expected the block's output but got %q, %v", r.stdout, r.err)
+	}
+
+	run("has-session", "-t", "missing")
+	lines.Scan()
+	io.WriteString(out, "%sessions-changed\n%begin 3 12 1\ncan't find session: missing\n%error 3 12 1\n")
+	r := <-results
+	if !errors.Is(commandError([]string{"has-session"}, r.stderr, r.err), ErrSessionNotFound) {
+		t.Errorf("Expected a failed command to match ErrSessionNotFound but got %q, %v", r.stderr, r.err)
+	}
+
+	want := []Notification{
+		{Name: "session-changed", Args: []string{"$1", "my session"}},
+		{Name: "sessions-changed"},
+	}
+	for _, w := range want {
+		n := <-c.Notifications()
+		if n.Name != w.Name || !slices.Equal(n.Args, w.Args) {
+			t.Errorf("Expected notification %+v but got %+v", w, n)
+		}
+	}
+
+	run("list-sessions")
+	lines.Scan()
+	io.WriteString(out, "%exit\n")
+	out.Close()
+	if r := <-results; !errors.Is(r.err, ErrControlClosed) {
+		t.Errorf("Expected pending command to fail with ErrControlClosed but got %v", r.err)
+	}
+	if _, ok := <-c.Notifications(); ok {
+		t.Error("Expected notifications to be closed")
+	}
+	if _, _, err := c.Run(ctx, []string{"list-sessions"}); !errors.Is(err, ErrControlClosed) {
+		t.Errorf("Expected command after exit to fail with ErrControlClosed but got %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Expected no error closing an exited client but got %v", err)
+	}
+}
+
+func TestControlCancel(t *testing.T) {
+	c, startup, lines, out := fakeControl(t)
+	io.WriteString(out, "%begin 1 10 0\n%end 1 10 0\n")
+	<-startup
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		lines.Scan()
+		cancel()
+	}()
+	if _, _, err := c.Run(ctx, []string{"list-sessions"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+
+	// NOTE: the late reply goes to the cancelled command, not the next one
+	results := make(chan string)
+	go func() {
+		stdout, _, _ := c.Run(context.Background(), []string{"display-message", "-p", "next"})
+		results <- stdout
+	}()
+	lines.Scan()
+	io.WriteString(out, "%begin 2 11 1\nlate\n%end 2 11 1\n%begin 2 12 1\nnext\n%end 2 12 1\n")
+	if got := <-results; got != "next\n" {
+		t.Errorf("Expected next but got %q", got)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	line, err := commandLine([]string{"new-session", "-c", "~/code", "-s", "a;b $HOME"})
+	if want := `'new-session' '-c' '~/code' '-s' 'a;b $HOME'`; err != nil || line != want {
+		t.Errorf("Expected %s but got %s, %v", want, line, err)
+	}
+	if _, err := commandLine([]string{"send-keys", "a\nb"}); err == nil {
+		t.Error("Expected error for arg with a newline")
+	}
+}
@@ -0,0 +1,143 @@
+package tmux
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Env is what tmux tells the processes in its panes about where they run, through
+// $TMUX and $TMUX_PANE
+type Env struct {
+	SocketPath string // socket of the server
+	PID        int    // PID of the server
+	SessionId  string // e.g. "$1"; the session the pane was created in
+	PaneId     string // e.g. "%3"; empty outside panes, e.g. in run-shell
+}
+
+// GetEnv parses $TMUX and $TMUX_PANE; returns ErrNotInTmux outside tmux
+func GetEnv() (Env, error) {
+	return parseEnv(os.Getenv("TMUX"), os.Getenv("TMUX_PANE"))
+}
+
+// parseEnv parses $TMUX, which is "<socket path>,<server pid>,<session id>", and $TMUX_PANE
+func parseEnv(tmuxVar string, paneVar string) (Env, error) {
+	if tmuxVar == "" {
+		return Env{}, ErrNotInTmux
+	}
+
+	// NOTE: split from the end since the socket path may contain commas
+	fields := strings.Split(tmuxVar, ",")
+	if len(fields) < 3 {
+		return Env{}, fmt.Errorf("unexpected $TMUX: %q", tmuxVar)
+	}
+	n := len(fields)
+	pid, err := strconv.Atoi(fields[n-2])
+	if err != nil {
+		return Env{}, fmt.Errorf("unexpected server PID in $TMUX: %q", tmuxVar)
+	}
+	if _, err := strconv.Atoi(fields[n-1]); err != nil {
+		return Env{}, fmt.Errorf("unexpected session ID in $TMUX: %q", tmuxVar)
+	}
+
+	return Env{
+		SocketPath: strings.Join(fields[:n-2], ","),
+		PID:        pid,
+		SessionId:  "$" + fields[n-1],
+		PaneId:     paneVar,
+	}, nil
+}
+
+// Current is the client, session and pane flow runs in
+type Current struct {
+	Client      string // name of the client, e.g. "/dev/pts/1"; empty if no client shows the session
+	SessionId   string
+	SessionName string
+	WindowId    string
+	PaneId      string
+}
+
+// GetCurrent retrieves the client, session and pane flow runs in from $TMUX and $TMUX_PANE.
+// Meant for the server from GetCurrentServer
+func (server *Server) GetCurrent(ctx context.Context) (*Current, error) {
+	env, err := GetEnv()
+	if err != nil {
+		return &Current{}, err
+	}
+
+	// NOTE: the pane is more reliable than the session in $TMUX, which is where the
+	// pane was created, since its window may have moved to another session since
+	target := env.PaneId
+	if target == "" {
+		target = env.SessionId
+	}
+	format := []string{
+		"#{session_id}",
+		"#{window_id}",
+		"#{pane_id}",
+		"#{session_name}", // NOTE: keep names last since they may contain the separator
+	}
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"display-message",
+		"-p",
+		"-t",
+		target,
+		strings.Join(format, tmuxFormatSep),
+	}
+	out, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return &Current{}, fmt.Errorf("couldn't retrieve current pane: %w", err)
+	}
+	fields := strings.SplitN(strings.TrimSpace(out), tmuxFormatSep, 4)
+	if len(fields) != 4 {
+		return &Current{}, fmt.Errorf("unexpected current pane data: %q", out)
+	}
+	current := &Current{
+		SessionId:   fields[0],
+		WindowId:    fields[1],
+		PaneId:      fields[2],
+		SessionName: fields[3],
+	}
+
+	client, err := server.currentClient(ctx, current.SessionId)
+	if err != nil {
+		return &Current{}, err
+	}
+	current.Client = client
+	return current, nil
+}
+
+// currentClient returns the most recently active client showing the session, if any.
+// Unlike #{client_name}, which falls back to a client of any session
+func (server *Server) currentClient(ctx context.Context, sessionId string) (string, error) {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"list-clients",
+		"-t",
+		sessionId,
+		"-F",
+		"#{client_activity}" + tmuxFormatSep + "#{client_name}",
+	}
+	out, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return "", fmt.Errorf("couldn't retrieve clients: %w", err)
+	}
+
+	var client string
+	latest := -1
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		activity, name, ok := strings.Cut(line, tmuxFormatSep)
+		if !ok {
+			continue
+		}
+		if t, err := strconv.Atoi(activity); err == nil && t > latest {
+			client, latest = name, t
+		}
+	}
+	return client, nil
+}
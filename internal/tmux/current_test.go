@@ -0,0 +1,86 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/winter-again/flow/internal/tmux/tmuxtest"
+)
+
+func TestParseEnv(t *testing.T) {
+	cases := []struct {
+		tmux    string
+		pane    string
+		want    Env
+		wantErr bool
+	}{
+		{
+			tmux: "/tmp/tmux-1000/default,4242,0",
+			pane: "%3",
+			want: Env{SocketPath: "/tmp/tmux-1000/default", PID: 4242, SessionId: "$0", PaneId: "%3"},
+		},
+		{
+			tmux: "/srv/odd,dir/sock,7,12",
+			want: Env{SocketPath: "/srv/odd,dir/sock", PID: 7, SessionId: "$12"},
+		},
+		{tmux: "/tmp/tmux-1000/default", wantErr: true},
+		{tmux: "/tmp/tmux-1000/default,pid,0", wantErr: true},
+		{tmux: "/tmp/tmux-1000/default,4242,$0", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := parseEnv(c.tmux, c.pane)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: expected error %v but got %v", c.tmux, c.wantErr, err)
+		}
+		if !c.wantErr && got != c.want {
+			t.Errorf("%s: expected %+v but got %+v", c.tmux, c.want, got)
+		}
+	}
+
+	if _, err := parseEnv("", "%3"); !errors.Is(err, ErrNotInTmux) {
+		t.Errorf("Expected ErrNotInTmux without $TMUX but got %v", err)
+	}
+}
+
+func TestGetCurrent(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TMUX", "/tmp/tmux-1000/work,4242,0")
+	t.Setenv("TMUX_PANE", "%3")
+	fake := (&tmuxtest.Fake{}).
+		On("display-message", tmuxtest.Response{Stdout: "$1;@2;%3;my;session\n"}).
+		On("list-clients", tmuxtest.Response{Stdout: "100;/dev/pts/1\n300;/dev/pts/4\n200;/dev/pts/2\n"})
+	server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+
+	current, err := server.GetCurrent(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := Current{Client: "/dev/pts/4", SessionId: "$1", SessionName: "my;session", WindowId: "@2", PaneId: "%3"}
+	if *current != want {
+		t.Errorf("Expected %+v but got %+v", want, *current)
+	}
+	// NOTE: the pane is the target, not the session in $TMUX, which may be out of date
+	calls := fake.Calls()
+	if i := slices.Index(calls[0], "-t"); i < 0 || calls[0][i+1] != "%3" {
+		t.Errorf("Expected display-message to target the pane but got %q", calls[0])
+	}
+	if i := slices.Index(calls[1], "-t"); i < 0 || calls[1][i+1] != "$1" {
+		t.Errorf("Expected list-clients to target the current session but got %q", calls[1])
+	}
+
+	t.Setenv("TMUX_PANE", "")
+	fake = (&tmuxtest.Fake{}).On("display-message", tmuxtest.Response{Stdout: "$0;@0;%0;main\n"})
+	server.Runner = fake
+	current, err = server.GetCurrent(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if current.Client != "" {
+		t.Errorf("Expected no client for a session nobody is attached to but got %s", current.Client)
+	}
+	if i := slices.Index(fake.Calls()[0], "-t"); i < 0 || fake.Calls()[0][i+1] != "$0" {
+		t.Errorf("Expected display-message to target the session in $TMUX but got %q", fake.Calls()[0])
+	}
+}
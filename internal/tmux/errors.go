@@ -0,0 +1,95 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrTmuxNotFound     = errors.New("couldn't find tmux in the PATH")
+	ErrNoServer         = errors.New("no tmux server running")
+	ErrServerExists     = errors.New("server already exists")
+	ErrNested           = errors.New("shouldn't nest tmux sessions")
+	ErrNotInTmux        = errors.New("not running inside tmux")
+	ErrSessionNotFound  = errors.New("session doesn't exist")
+	ErrDuplicateSession = errors.New("session already exists")
+)
+
+// CommandError is returned when tmux runs but fails. It matches ErrNoServer,
+// ErrSessionNotFound and ErrDuplicateSession with errors.Is based on tmux's message
+type CommandError struct {
+	Args     []string // args tmux was run with
+	ExitCode int      // -1 if tmux couldn't be run at all
+	Stderr   string
+	Err      error
+}
+
+func (e *CommandError) Error() string {
+	msg := e.Stderr
+	if msg == "" || errors.Is(e.Err, context.DeadlineExceeded) || errors.Is(e.Err, context.Canceled) {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("tmux %s failed: %s", commandName(e.Args), msg)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CommandError) Is(target error) bool {
+	// NOTE: tmux exits with 1 for any failure, so the message is all there is to go on
+	switch target {
+	case ErrNoServer:
+		// NOTE: a server without sessions exits, so "no sessions" means there's no server
+		return strings.Contains(e.Stderr, "no server running") || strings.Contains(e.Stderr, "error connecting to") ||
+			e.Stderr == "no sessions"
+	case ErrSessionNotFound:
+		return strings.Contains(e.Stderr, "can't find session")
+	case ErrDuplicateSession:
+		return strings.Contains(e.Stderr, "duplicate session")
+	}
+	return false
+}
+
+// commandError wraps the error of running tmux with args in a CommandError
+func commandError(args []string, stderr string, err error) error {
+	if err == nil || errors.Is(err, ErrTmuxNotFound) {
+		return err
+	}
+
+	code := -1
+	var exit interface{ ExitCode() int }
+	if errors.As(err, &exit) {
+		code = exit.ExitCode()
+	}
+	return &CommandError{
+		Args:     args,
+		ExitCode: code,
+		Stderr:   strings.TrimSpace(stderr),
+		Err:      err,
+	}
+}
+
+// commandName returns the tmux command in args
+func commandName(args []string) string {
+	if args = commandArgs(args); len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
+// commandArgs returns the tmux command in args and its args, skipping the global flags before it
+func commandArgs(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-S" || args[i] == "-L" || args[i] == "-f":
+			i++ // NOTE: these take a value
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The tests in this file run a real tmux server and are skipped if tmux isn't installed.
+// Each one gets its own server in a temporary TMUX_TMPDIR, so they never touch the
+// user's default server or the one they're run from
+
+// isolatedRunner runs tmux without the user's config so that tests don't depend on it
+type isolatedRunner struct{}
+
+func (isolatedRunner) Run(ctx context.Context, args []string) (string, string, error) {
+	return ExecRunner{}.Run(ctx, append([]string{"-f", "/dev/null"}, args...))
+}
+
+// newTestServer returns a server on the default socket of a temporary socket dir.
+// The server is killed when the test ends, whether or not it passed
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux isn't installed")
+	}
+
+	// NOTE: not t.TempDir() since socket paths are limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "flow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	t.Setenv("TMUX_TMPDIR", dir)
+	t.Setenv("TMUX", "")
+	InitSessionName = "0"
+
+	server := NewServer(GetDefaultSocket())
+	server.Runner = isolatedRunner{}
+	t.Cleanup(func() {
+		server.cmd(context.Background(), []string{"-S", server.SocketPath, "kill-server"})
+	})
+	return server
+}
+
+// startTestServer is newTestServer with the server already running
+func startTestServer(t *testing.T) *Server {
+	t.Helper()
+	server := newTestServer(t)
+	if _, stderr, err := server.Start(context.Background()); err != nil {
+		t.Fatalf("Couldn't start server: %v: %s", err, stderr)
+	}
+	return server
+}
+
+// attachControlClient attaches a control mode client to the session, which stands in
+// for a terminal, and returns the client's name
+func attachControlClient(t *testing.T, server *Server, sessionName string) string {
+	t.Helper()
+	cmd := exec.Command("tmux", "-f", "/dev/null", "-S", server.SocketPath, "-C", "attach-session", "-t", "="+sessionName)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Couldn't attach control client: %v", err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	var client string
+	waitFor(t, "control client to attach", func() bool {
+		for _, c := range listClients(t, server) {
+			if c[1] == sessionName {
+				client = c[0]
+				return true
+			}
+		}
+		return false
+	})
+	return client
+}
+
+// listClients returns the name and session of every client of the server
+func listClients(t *testing.T, server *Server) [][2]string {
+	t.Helper()
+	out, _, err := server.cmd(context.Background(), []string{"-S", server.SocketPath, "list-clients", "-F", "#{client_name};#{session_name}"})
+	if err != nil {
+		t.Fatalf("Couldn't list clients: %v", err)
+	}
+
+	var clients [][2]string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if name, session, ok := strings.Cut(line, ";"); ok {
+			clients = append(clients, [2]string{name, session})
+		}
+	}
+	return clients
+}
+
+// waitFor polls cond until it's true, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestIntegrationStart(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	if _, err := server.GetSessions(ctx); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Expected ErrNoServer before starting but got %v", err)
+	}
+
+	if _, stderr, err := server.Start(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v: %s", err, stderr)
+	}
+	if !server.SessionExists(ctx, InitSessionName) {
+		t.Errorf("Expected initial session %s to exist", InitSessionName)
+	}
+	if _, _, err := server.Start(ctx); err == nil {
+		t.Error("Expected error when starting a running server")
+	}
+
+	// NOTE: a named socket in the same dir is a separate server
+	other := NewServer("other", server.SocketPath)
+	other.Runner = isolatedRunner{}
+	t.Cleanup(func() { other.cmd(context.Background(), []string{"-S", other.SocketPath, "kill-server"}) })
+	if _, stderr, err := other.Start(ctx); err != nil {
+		t.Fatalf("Unexpected error starting second server: %v: %s", err, stderr)
+	}
+	if sessions, err := server.GetSessions(ctx); err != nil || len(sessions) != 1 {
+		t.Errorf("Expected first server to still have 1 session but got %v, %v", sessions, err)
+	}
+}
+
+func TestIntegrationSessions(t *testing.T) {
+	ctx := context.Background()
+	server := startTestServer(t)
+	dir := t.TempDir()
+
+	session, err := server.CreateSession(ctx, "example.com", dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if session.Name != "example_com" || session.Path != dir || session.Windows != 1 {
+		t.Errorf("Unexpected session: %+v", *session)
+	}
+	if _, err := server.CreateSession(ctx, "example_com", dir); !errors.Is(err, ErrDuplicateSession) {
+		t.Errorf("Expected ErrDuplicateSession creating a duplicate session but got %v", err)
+	}
+
+	window, err := server.NewWindow(ctx, session.Id, "logs", dir)
+	if err != nil {
+		t.Fatalf("Unexpected error creating window: %v", err)
+	}
+	if _, err := server.SplitWindow(ctx, window.Id, dir, true); err != nil {
+		t.Fatalf("Unexpected error splitting window: %v", err)
+	}
+	windows, err := server.GetWindows(ctx, session)
+	if err != nil || len(windows) != 2 {
+		t.Fatalf("Expected 2 windows but got %v, %v", windows, err)
+	}
+	if windows[1].Name != "logs" || windows[1].Panes != 2 {
+		t.Errorf("Unexpected window: %+v", *windows[1])
+	}
+	panes, err := server.GetPanes(ctx, windows[1])
+	if err != nil || len(panes) != 2 || panes[1].CurrentPath != dir {
+		t.Errorf("Expected 2 panes in %s but got %v, %v", dir, panes, err)
+	}
+
+	sessions, err := server.GetSessions(ctx)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions but got %v, %v", sessions, err)
+	}
+
+	if err := server.KillSession(ctx, session.Name); err != nil {
+		t.Fatalf("Unexpected error killing session: %v", err)
+	}
+	if server.SessionExists(ctx, session.Name) {
+		t.Errorf("Expected session %s to be gone", session.Name)
+	}
+	if err := server.KillSession(ctx, session.Name); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound killing a missing session but got %v", err)
+	}
+}
+
+func TestIntegrationExactSessionNames(t *testing.T) {
+	ctx := context.Background()
+	server := startTestServer(t)
+	dir := t.TempDir()
+
+	// NOTE: tmux matches targets by prefix, so "api" would find "api-server" without "="
+	if _, err := server.CreateSession(ctx, "api-server", dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if server.SessionExists(ctx, "api") {
+		t.Error("Expected session api not to exist next to api-server")
+	}
+	if _, _, err := server.Attach(ctx, "api"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound attaching to api but got %v", err)
+	}
+
+	if _, err := server.CreateSession(ctx, "api", dir); err != nil {
+		t.Fatalf("Unexpected error creating api next to api-server: %v", err)
+	}
+	if !server.SessionExists(ctx, "api") || !server.SessionExists(ctx, "api-server") {
+		t.Error("Expected both api and api-server to exist")
+	}
+}
+
+func TestIntegrationSeparatorInNames(t *testing.T) {
+	ctx := context.Background()
+	server := startTestServer(t)
+
+	// NOTE: flow doesn't create such names but other clients can
+	args := []string{"-S", server.SocketPath, "new-session", "-d", "-s", "a;b", "-n", "w;x", "-c", t.TempDir()}
+	if _, stderr, err := server.cmd(ctx, args); err != nil {
+		t.Fatalf("Couldn't create session: %v: %s", err, stderr)
+	}
+	windows, err := server.GetWindows(ctx, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var found bool
+	for _, w := range windows {
+		if w.Name == "w;x" {
+			found = true
+			if w.SessionName != "a;b" {
+				t.Errorf("Expected session name a;b but got %q", w.SessionName)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a window named w;x but got %v", windows)
+	}
+}
+
+func TestIntegrationSwitchClient(t *testing.T) {
+	ctx := context.Background()
+	server := startTestServer(t)
+	if _, err := server.CreateSession(ctx, "work", t.TempDir()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := attachControlClient(t, server, InitSessionName)
+
+	if err := server.SwitchClient(ctx, client, "work"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitFor(t, "client to switch", func() bool {
+		clients := listClients(t, server)
+		return len(clients) == 1 && clients[0] == [2]string{client, "work"}
+	})
+
+	if err := server.SwitchClient(ctx, client, "missing"); err == nil {
+		t.Error("Expected error switching to a missing session")
+	}
+}
+
+func TestIntegrationControl(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t)
+	if _, err := server.Connect(ctx); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Expected ErrNoServer before starting but got %v", err)
+	}
+	if _, stderr, err := server.Start(ctx); err != nil {
+		t.Fatalf("Couldn't start server: %v: %s", err, stderr)
+	}
+
+	ctrl, err := server.Connect(ctx)
+	if err != nil {
+		t.Fatalf("Couldn't connect: %v", err)
+	}
+	defer ctrl.Close()
+
+	conn := *server
+	conn.Runner = ctrl
+	session, err := conn.CreateSession(ctx, "work", t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := conn.GetSession(ctx, "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound but got %v", err)
+	}
+
+	// NOTE: changes made by other clients are reported too
+	if _, _, err := server.cmd(ctx, []string{"-S", server.SocketPath, "rename-session", "-t", session.Id, "deep work"}); err != nil {
+		t.Fatalf("Couldn't rename session: %v", err)
+	}
+	timeout := time.After(5 * time.Second)
+	for renamed := false; !renamed; {
+		select {
+		case n, ok := <-ctrl.Notifications():
+			if !ok {
+				t.Fatalf("Control client exited: %v", ctrl.Err())
+			}
+			renamed = n.Name == "session-renamed" && len(n.Args) == 2 && n.Args[0] == session.Id && n.Args[1] == "deep work"
+		case <-timeout:
+			t.Fatal("Timed out waiting for session-renamed")
+		}
+	}
+
+	if err := ctrl.Close(); err != nil {
+		t.Errorf("Unexpected error closing: %v", err)
+	}
+	if _, err := conn.GetSessions(ctx); !errors.Is(err, ErrControlClosed) {
+		t.Errorf("Expected ErrControlClosed after closing but got %v", err)
+	}
+}
+
+func TestIntegrationCurrent(t *testing.T) {
+	ctx := context.Background()
+	server := startTestServer(t)
+	session, err := server.CreateSession(ctx, "work", t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := attachControlClient(t, server, "work")
+	windows, err := server.GetWindows(ctx, session)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	panes, err := server.GetPanes(ctx, windows[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pane := panes[0]
+
+	// NOTE: what tmux would set for a process in the pane
+	t.Setenv("TMUX", server.SocketPath+",1,"+strings.TrimPrefix(pane.SessionId, "$"))
+	t.Setenv("TMUX_PANE", pane.Id)
+	current, err := server.GetCurrent(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if current.Client != client || current.SessionName != "work" || current.PaneId != pane.Id {
+		t.Errorf("Expected client %s in session work and pane %s but got %+v", client, pane.Id, *current)
+	}
+}
@@ -0,0 +1,83 @@
+package tmux
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Timeout bounds every tmux command run by a Server, except attaching, so that
+// a wedged server can't hang flow. Zero means no timeout
+var Timeout time.Duration
+
+// waitDelay is how long to wait for tmux's output once it has exited or been killed
+const waitDelay = time.Second
+
+// Runner runs tmux with the given args; returns stdout and stderr
+type Runner interface {
+	Run(ctx context.Context, args []string) (string, string, error)
+}
+
+// ExecRunner runs the tmux binary found in the PATH. The process is killed
+// if ctx is done before it exits
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, args []string) (string, string, error) {
+	tmux, err := exec.LookPath("tmux")
+	if err != nil {
+		return "", "", ErrTmuxNotFound
+	}
+
+	cmd := exec.CommandContext(ctx, tmux, args...)
+	// NOTE: tmux hands the client's stdio to the server, so a stuck server keeps the
+	// pipes open after the client is killed; stop waiting on them eventually
+	cmd.WaitDelay = waitDelay
+
+	var stdout, stderr bytes.Buffer
+	// NOTE: setting stdin makes it so that creating and attach to server works
+	// but does it make sense?
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	// NOTE: report why the process was killed rather than just "signal: killed"
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	outStr, errStr := stdout.String(), stderr.String()
+	return outStr, errStr, err
+}
+
+// DefaultRunner runs the commands of servers without their own Runner and of Cmd
+var DefaultRunner Runner = ExecRunner{}
+
+// Cmd runs a tmux command with given args; returns stdout and stderr. Failures
+// of tmux itself are returned as a *CommandError. Unlike Server methods, it
+// doesn't apply Timeout since it's also used for interactive commands
+func Cmd(ctx context.Context, args []string) (string, string, error) {
+	stdout, stderr, err := DefaultRunner.Run(ctx, args)
+	return stdout, stderr, commandError(args, stderr, err)
+}
+
+// cmd runs a tmux command using the server's runner, bounded by Timeout
+func (server *Server) cmd(ctx context.Context, args []string) (string, string, error) {
+	if Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+		defer cancel()
+	}
+	return server.interactive(ctx, args)
+}
+
+// interactive runs a tmux command that waits on the user, so without Timeout
+func (server *Server) interactive(ctx context.Context, args []string) (string, string, error) {
+	runner := server.Runner
+	if runner == nil {
+		runner = DefaultRunner
+	}
+	stdout, stderr, err := runner.Run(ctx, args)
+	return stdout, stderr, commandError(args, stderr, err)
+}
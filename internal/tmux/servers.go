@@ -0,0 +1,98 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/winter-again/flow/internal/shell"
+)
+
+// dialTimeout bounds connecting to a socket to see if its server is alive
+const dialTimeout = time.Second
+
+// GetSocketDir returns the directory of the current user's tmux sockets, where -L names point
+func GetSocketDir() string {
+	return filepath.Join(getSocketDir(), "tmux-"+getUID())
+}
+
+// ListServers returns a server for every socket in the socket dir, sorted by name. The
+// socket of a server that's gone stays behind, so check whether each one is Alive
+func ListServers() ([]*Server, error) {
+	dir := GetSocketDir()
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Server{}, nil
+	}
+	if err != nil {
+		return []*Server{}, fmt.Errorf("couldn't read socket dir: %w", err)
+	}
+
+	servers := []*Server{}
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSocket == 0 {
+			continue
+		}
+		servers = append(servers, &Server{
+			SocketName: entry.Name(),
+			SocketPath: filepath.Join(dir, entry.Name()),
+		})
+	}
+	slices.SortFunc(servers, func(a, b *Server) int {
+		return strings.Compare(a.SocketName, b.SocketName)
+	})
+	return servers, nil
+}
+
+// Alive checks whether a server is listening on the socket. Unlike asking tmux,
+// this never starts a server
+func (server *Server) Alive(ctx context.Context) (bool, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "unix", server.SocketPath)
+	if err == nil {
+		conn.Close()
+		return true, nil
+	}
+	// NOTE: a socket nobody listens on anymore refuses connections
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("couldn't connect to %s: %w", server.SocketPath, err)
+}
+
+// MoveClient moves a client of the server, or the current one if client is empty, to a
+// session of another server. A client can't switch across servers, so it's detached
+// and replaced by one attached to the other server
+func (server *Server) MoveClient(ctx context.Context, client string, other *Server, sessionName string) error {
+	attach := strings.Join([]string{
+		"exec",
+		"tmux",
+		"-S",
+		shell.Quote(other.SocketPath),
+		"attach-session",
+		"-t",
+		shell.Quote("=" + sessionName),
+	}, " ")
+
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"detach-client",
+	}
+	if client != "" {
+		args = append(args, "-t", client)
+	}
+	args = append(args, "-E", attach) // NOTE: runs attach in place of the client
+
+	if _, _, err := server.cmd(ctx, args); err != nil {
+		return fmt.Errorf("couldn't move client to session %s of server %s: %w", sessionName, other.SocketName, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package tmux
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/winter-again/flow/internal/tmux/tmuxtest"
+)
+
+func TestListServers(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	if servers, err := ListServers(); err != nil || len(servers) != 0 {
+		t.Errorf("Expected no servers without a socket dir but got %v, %v", servers, err)
+	}
+
+	dir := GetSocketDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	listenUnix(t, filepath.Join(dir, "work"))
+	staleSocket(t, filepath.Join(dir, "crashed"))
+	if err := os.WriteFile(filepath.Join(dir, "notes"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, err := ListServers()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(servers) != 2 || servers[0].SocketName != "crashed" || servers[1].SocketName != "work" {
+		t.Fatalf("Expected servers crashed and work but got %v", servers)
+	}
+	if servers[1].SocketPath != filepath.Join(dir, "work") {
+		t.Errorf("Expected socket path %s but got %s", filepath.Join(dir, "work"), servers[1].SocketPath)
+	}
+
+	for server, want := range map[*Server]bool{servers[0]: false, servers[1]: true} {
+		alive, err := server.Alive(ctx)
+		if err != nil || alive != want {
+			t.Errorf("Expected %s to be alive %v but got %v, %v", server.SocketName, want, alive, err)
+		}
+	}
+	missing := &Server{SocketPath: filepath.Join(dir, "missing")}
+	if alive, err := missing.Alive(ctx); alive || err != nil {
+		t.Errorf("Expected missing socket to be dead without error but got %v, %v", alive, err)
+	}
+}
+
+func TestMoveClient(t *testing.T) {
+	ctx := context.Background()
+	fake := &tmuxtest.Fake{}
+	server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+	other := &Server{SocketName: "client's", SocketPath: "/tmp/tmux-1000/client's"}
+
+	if err := server.MoveClient(ctx, "", other, "api"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := [][]string{{
+		"-S", "/tmp/tmux-1000/work", "detach-client",
+		"-E", `exec tmux -S '/tmp/tmux-1000/client'\''s' attach-session -t '=api'`,
+	}}
+	if !equalCalls(fake.Calls(), want) {
+		t.Errorf("Expected calls %q but got %q", want, fake.Calls())
+	}
+}
@@ -1,12 +1,11 @@
 package tmux
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
 	"strconv"
@@ -15,7 +14,6 @@ import (
 
 const tmuxFormatSep string = ";"
 
-// TODO: should define a sentinel tmux error to return from this?
 var InitSessionName string
 
 // InsideTmux checks if $TMUX environment var is set, meaning running inside tmux
@@ -29,6 +27,7 @@ func InsideTmux() bool {
 type Server struct {
 	SocketName string // socket name
 	SocketPath string // socket path
+	Runner     Runner // runs tmux commands; DefaultRunner if nil
 }
 
 // NewServer creates Server spec based on socket name or path or just the default.
@@ -59,39 +58,29 @@ func NewServer(socketName string, socketPath string) *Server {
 	}
 }
 
-// GetCurrentServer retrieves the server for the current session
+// GetCurrentServer retrieves the server flow runs in from $TMUX, whichever socket it uses
 func GetCurrentServer() (*Server, error) {
-	args := []string{
-		"list-clients",
-		"-F",
-		"#{socket_path}",
-	}
-	serverInfo, _, err := Cmd(args)
+	env, err := GetEnv()
 	if err != nil {
-		return &Server{}, errors.New("problem fetching server")
+		return &Server{}, err
 	}
-
-	// NOTE: assumes only 1 server running and takes first
-	sockets := strings.Split(serverInfo, "\n")
-	socketPath := strings.TrimSpace(sockets[0])
-
 	return &Server{
-		SocketName: filepath.Base(socketPath),
-		SocketPath: socketPath,
+		SocketName: filepath.Base(env.SocketPath),
+		SocketPath: env.SocketPath,
 	}, nil
 }
 
 // Start starts a new tmux server with a single session using either the
 // socket name or the socket path
-func (server *Server) Start() (string, string, error) {
+func (server *Server) Start(ctx context.Context) (string, string, error) {
 	if InsideTmux() {
-		return "", "", errors.New("shouldn't nest tmux sessions")
+		return "", "", ErrNested
 	}
 
-	// NOTE: assumes that server is running if the socket exists,
-	// though it's possible to just delete the socket while server runs
-	if _, err := os.Stat(server.SocketPath); err == nil {
-		return "", "", errors.New("server already exists")
+	// NOTE: a socket can outlive its server, and tmux replaces such stale sockets,
+	// so only a server that's listening counts
+	if alive, _ := server.Alive(ctx); alive {
+		return "", "", ErrServerExists
 	}
 
 	_, defaultSocketPath := GetDefaultSocket()
@@ -119,7 +108,7 @@ func (server *Server) Start() (string, string, error) {
 		}
 	}
 
-	stdout, stderr, err := Cmd(args)
+	stdout, stderr, err := server.cmd(ctx, args)
 	if err != nil {
 		return stdout, stderr, err
 	}
@@ -128,9 +117,9 @@ func (server *Server) Start() (string, string, error) {
 
 // Attach attaches to session in the given server.
 // If no target session is given, tmux will pref most recently used unattached session
-func (server *Server) Attach(sessionName string) (string, string, error) {
+func (server *Server) Attach(ctx context.Context, sessionName string) (string, string, error) {
 	if InsideTmux() {
-		return "", "", errors.New("shouldn't nest tmux sessions")
+		return "", "", ErrNested
 	}
 
 	// NOTE: attach-session will try to create server, but this will fail
@@ -142,13 +131,17 @@ func (server *Server) Attach(sessionName string) (string, string, error) {
 	}
 
 	if sessionName != "" {
-		if !server.SessionExists(sessionName) {
-			return "", "", errors.New("session doesn't exist")
+		if err := server.hasSession(ctx, sessionName); err != nil {
+			if errors.Is(err, ErrNoServer) {
+				return "", "", err
+			}
+			return "", "", fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
 		}
-		args = append(args, "-t", sessionName)
+		// NOTE: prepending "=" to session name enforces only exact matches
+		args = append(args, "-t", "="+sessionName)
 	}
 
-	stdout, stderr, err := Cmd(args)
+	stdout, stderr, err := server.interactive(ctx, args)
 	if err != nil {
 		return stdout, stderr, err
 	}
@@ -183,19 +176,16 @@ func getUID() string {
 }
 
 type Session struct {
-	Id      string // unique session ID
-	Name    string // name of session
-	Path    string // working directory of session
-	Windows int    // number of windows in session
+	Id       string // unique session ID
+	Name     string // name of session
+	Path     string // working directory of session
+	Windows  int    // number of windows in session
+	Attached int    // number of clients attached to session
 }
 
 // GetSession retrieves a tmux session by name
-func (server *Server) GetSession(sessionName string) (*Session, error) {
-	if !server.SessionExists(sessionName) {
-		return &Session{}, fmt.Errorf("session %s doesn't exist", sessionName)
-	}
-
-	sessions, err := server.GetSessions()
+func (server *Server) GetSession(ctx context.Context, sessionName string) (*Session, error) {
+	sessions, err := server.GetSessions(ctx)
 	if err != nil {
 		return &Session{}, err
 	}
@@ -205,16 +195,17 @@ func (server *Server) GetSession(sessionName string) (*Session, error) {
 			return session, nil
 		}
 	}
-	return &Session{}, fmt.Errorf("session %s doesn't exist", sessionName)
+	return &Session{}, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
 }
 
 // GetSessions retrieves all tmux sessions
-func (server *Server) GetSessions() ([]*Session, error) {
+func (server *Server) GetSessions(ctx context.Context) ([]*Session, error) {
 	format := []string{
 		"#{session_id}",
 		"#{session_name}",
 		"#{session_path}",
 		"#{session_windows}",
+		"#{session_attached}",
 	}
 	args := []string{
 		"-S",
@@ -223,7 +214,7 @@ func (server *Server) GetSessions() ([]*Session, error) {
 		"-F",
 		strings.Join(format, tmuxFormatSep),
 	}
-	sessions, _, err := Cmd(args)
+	sessions, _, err := server.cmd(ctx, args)
 	if err != nil {
 		return []*Session{}, fmt.Errorf("couldn't retrieve sessions: %w", err)
 	}
@@ -237,56 +228,474 @@ func (server *Server) GetSessions() ([]*Session, error) {
 
 // parseSessions parses returned tmux session data into Session struct
 func parseSessions(sessionsOutput string) ([]*Session, error) {
-	sessions := strings.Split(strings.TrimSpace(sessionsOutput), "\n")
+	sessionsOutput = strings.TrimSpace(sessionsOutput)
+	if sessionsOutput == "" {
+		return []*Session{}, nil
+	}
+	sessions := strings.Split(sessionsOutput, "\n")
 
 	sessionsParsed := make([]*Session, len(sessions))
 	for i, s := range sessions {
 		fields := strings.Split(s, tmuxFormatSep)
+		if len(fields) != 5 {
+			return []*Session{}, fmt.Errorf("unexpected number of session fields: %q", s)
+		}
 		nWins, err := strconv.Atoi(fields[3])
 		if err != nil {
 			return []*Session{}, errors.New("error parsing number of windows per session")
 		}
+		nClients, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return []*Session{}, errors.New("error parsing number of clients per session")
+		}
 		session := &Session{
-			Id:      fields[0],
-			Name:    fields[1],
-			Path:    fields[2],
-			Windows: nWins,
+			Id:       fields[0],
+			Name:     fields[1],
+			Path:     fields[2],
+			Windows:  nWins,
+			Attached: nClients,
 		}
 		sessionsParsed[i] = session
 	}
 	return sessionsParsed, nil
 }
 
+// windowFormat is the list-windows format parsed by parseWindows. The session name
+// isn't part of it since only one field that may contain the separator can come last;
+// setSessionNames looks it up by ID instead
+var windowFormat = strings.Join([]string{
+	"#{window_id}",
+	"#{session_id}",
+	"#{window_index}",
+	"#{?window_active,1,0}",
+	"#{window_layout}",
+	"#{window_panes}",
+	"#{window_width}",
+	"#{window_height}",
+	"#{window_name}", // NOTE: keep the name last since it may contain the separator
+}, tmuxFormatSep)
+
+type Window struct {
+	Id          string // unique window ID
+	SessionId   string // ID of session the window belongs to
+	SessionName string // name of session the window belongs to
+	Index       int    // index of window in session
+	Name        string // name of window
+	Active      bool   // whether window is the active window in its session
+	Layout      string // window layout string
+	Panes       int    // number of panes in window
+	Width       int    // width of window in cells
+	Height      int    // height of window in cells
+}
+
+// GetWindows retrieves the windows of a session, or all windows in the server if session is nil
+func (server *Server) GetWindows(ctx context.Context, session *Session) ([]*Window, error) {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"list-windows",
+	}
+	if session == nil {
+		args = append(args, "-a")
+	} else {
+		args = append(args, "-t", session.Id)
+	}
+	args = append(args, "-F", windowFormat)
+
+	windows, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return []*Window{}, fmt.Errorf("couldn't retrieve windows: %w", err)
+	}
+
+	parsedWindows, err := parseWindows(windows)
+	if err != nil {
+		return []*Window{}, fmt.Errorf("couldn't parse window data: %w", err)
+	}
+	if err := server.setSessionNames(ctx, parsedWindows); err != nil {
+		return []*Window{}, err
+	}
+	return parsedWindows, nil
+}
+
+// setSessionNames fills in the session names of windows from their session IDs
+func (server *Server) setSessionNames(ctx context.Context, windows []*Window) error {
+	if len(windows) == 0 {
+		return nil
+	}
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"list-sessions",
+		"-F",
+		"#{session_id}" + tmuxFormatSep + "#{session_name}",
+	}
+	out, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve session names: %w", err)
+	}
+
+	names := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		// NOTE: IDs never contain the separator, so the rest of the line is the name
+		if id, name, ok := strings.Cut(line, tmuxFormatSep); ok {
+			names[id] = name
+		}
+	}
+	for _, w := range windows {
+		w.SessionName = names[w.SessionId]
+	}
+	return nil
+}
+
+// parseWindows parses returned tmux window data into Window struct
+func parseWindows(windowsOutput string) ([]*Window, error) {
+	windowsOutput = strings.TrimSpace(windowsOutput)
+	if windowsOutput == "" {
+		return []*Window{}, nil
+	}
+	windows := strings.Split(windowsOutput, "\n")
+
+	windowsParsed := make([]*Window, len(windows))
+	for i, w := range windows {
+		fields := strings.SplitN(w, tmuxFormatSep, 9)
+		if len(fields) != 9 {
+			return []*Window{}, fmt.Errorf("unexpected number of window fields: %q", w)
+		}
+		nums, err := atoiFields(fields[2], fields[5], fields[6], fields[7])
+		if err != nil {
+			return []*Window{}, fmt.Errorf("error parsing window fields: %w", err)
+		}
+		windowsParsed[i] = &Window{
+			Id:        fields[0],
+			SessionId: fields[1],
+			Index:     nums[0],
+			Active:    fields[3] == "1",
+			Layout:    fields[4],
+			Panes:     nums[1],
+			Width:     nums[2],
+			Height:    nums[3],
+			Name:      fields[8],
+		}
+	}
+	return windowsParsed, nil
+}
+
+// paneFormat is the list-panes format parsed by parsePanes
+var paneFormat = strings.Join([]string{
+	"#{pane_id}",
+	"#{window_id}",
+	"#{session_id}",
+	"#{pane_index}",
+	"#{?pane_active,1,0}",
+	"#{pane_pid}",
+	"#{pane_width}",
+	"#{pane_height}",
+	"#{pane_current_command}",
+	"#{pane_current_path}", // NOTE: keep paths last since they may contain the separator
+}, tmuxFormatSep)
+
+type Pane struct {
+	Id             string // unique pane ID
+	WindowId       string // ID of window the pane belongs to
+	SessionId      string // ID of session the pane belongs to
+	Index          int    // index of pane in window
+	Active         bool   // whether pane is the active pane in its window
+	CurrentCommand string // command currently running in pane
+	CurrentPath    string // current working directory of pane
+	PID            int    // PID of the first process in pane
+	Width          int    // width of pane in cells
+	Height         int    // height of pane in cells
+}
+
+// GetPanes retrieves the panes of a window, or all panes in the server if window is nil
+func (server *Server) GetPanes(ctx context.Context, window *Window) ([]*Pane, error) {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"list-panes",
+	}
+	if window == nil {
+		args = append(args, "-a")
+	} else {
+		args = append(args, "-t", window.Id)
+	}
+	args = append(args, "-F", paneFormat)
+
+	panes, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return []*Pane{}, fmt.Errorf("couldn't retrieve panes: %w", err)
+	}
+
+	parsedPanes, err := parsePanes(panes)
+	if err != nil {
+		return []*Pane{}, fmt.Errorf("couldn't parse pane data: %w", err)
+	}
+	return parsedPanes, nil
+}
+
+// parsePanes parses returned tmux pane data into Pane struct
+func parsePanes(panesOutput string) ([]*Pane, error) {
+	panesOutput = strings.TrimSpace(panesOutput)
+	if panesOutput == "" {
+		return []*Pane{}, nil
+	}
+	panes := strings.Split(panesOutput, "\n")
+
+	panesParsed := make([]*Pane, len(panes))
+	for i, p := range panes {
+		fields := strings.SplitN(p, tmuxFormatSep, 10)
+		if len(fields) != 10 {
+			return []*Pane{}, fmt.Errorf("unexpected number of pane fields: %q", p)
+		}
+		nums, err := atoiFields(fields[3], fields[5], fields[6], fields[7])
+		if err != nil {
+			return []*Pane{}, fmt.Errorf("error parsing pane fields: %w", err)
+		}
+		panesParsed[i] = &Pane{
+			Id:             fields[0],
+			WindowId:       fields[1],
+			SessionId:      fields[2],
+			Index:          nums[0],
+			Active:         fields[4] == "1",
+			PID:            nums[1],
+			Width:          nums[2],
+			Height:         nums[3],
+			CurrentCommand: fields[8],
+			CurrentPath:    fields[9],
+		}
+	}
+	return panesParsed, nil
+}
+
+// NewWindow creates a window in the target session with the given name and working directory
+func (server *Server) NewWindow(ctx context.Context, target string, windowName string, windowPath string) (*Window, error) {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"new-window",
+		"-d",
+		"-P", // print info about new window
+		"-F",
+		windowFormat,
+		"-t",
+		target + ":", // NOTE: trailing colon targets the next free index of the session
+		"-c",
+		windowPath,
+	}
+	if windowName != "" {
+		args = append(args, "-n", windowName)
+	}
+	out, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return &Window{}, fmt.Errorf("couldn't create window: %w", err)
+	}
+
+	windows, err := parseWindows(out)
+	if err != nil {
+		return &Window{}, fmt.Errorf("couldn't parse new window data: %w", err)
+	}
+	if len(windows) != 1 {
+		return &Window{}, fmt.Errorf("expected 1 new window but found %d", len(windows))
+	}
+	if err := server.setSessionNames(ctx, windows); err != nil {
+		return &Window{}, err
+	}
+	return windows[0], nil
+}
+
+// RenameWindow renames the target window
+func (server *Server) RenameWindow(ctx context.Context, target string, windowName string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"rename-window",
+		"-t",
+		target,
+		windowName,
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't rename window: %w", err)
+	}
+	return nil
+}
+
+// SelectWindow makes the target window the active window of its session
+func (server *Server) SelectWindow(ctx context.Context, target string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"select-window",
+		"-t",
+		target,
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't select window: %w", err)
+	}
+	return nil
+}
+
+// SelectLayout arranges the panes of the target window using a preset or custom layout
+func (server *Server) SelectLayout(ctx context.Context, target string, layout string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"select-layout",
+		"-t",
+		target,
+		layout,
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't select layout %s: %w", layout, err)
+	}
+	return nil
+}
+
+// SplitWindow splits the target pane, creating a new pane with the given working directory.
+// Splits are top and bottom unless horizontal is set
+func (server *Server) SplitWindow(ctx context.Context, target string, panePath string, horizontal bool) (*Pane, error) {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"split-window",
+		"-d",
+		"-P", // print info about new pane
+		"-F",
+		paneFormat,
+		"-t",
+		target,
+		"-c",
+		panePath,
+	}
+	if horizontal {
+		args = append(args, "-h")
+	}
+	out, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return &Pane{}, fmt.Errorf("couldn't split window: %w", err)
+	}
+
+	panes, err := parsePanes(out)
+	if err != nil {
+		return &Pane{}, fmt.Errorf("couldn't parse new pane data: %w", err)
+	}
+	if len(panes) != 1 {
+		return &Pane{}, fmt.Errorf("expected 1 new pane but found %d", len(panes))
+	}
+	return panes[0], nil
+}
+
+// RespawnPane restarts the target pane's shell in the given working directory,
+// killing whatever is running in it
+func (server *Server) RespawnPane(ctx context.Context, target string, panePath string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"respawn-pane",
+		"-k",
+		"-t",
+		target,
+		"-c",
+		panePath,
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't respawn pane: %w", err)
+	}
+	return nil
+}
+
+// SelectPane makes the target pane the active pane of its window
+func (server *Server) SelectPane(ctx context.Context, target string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"select-pane",
+		"-t",
+		target,
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't select pane: %w", err)
+	}
+	return nil
+}
+
+// SendCommand types a command into the target pane and presses enter
+func (server *Server) SendCommand(ctx context.Context, target string, command string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"send-keys",
+		"-t",
+		target,
+		"-l", // literal, so words like "Enter" in the command aren't treated as keys
+		command,
+	}
+	if _, _, err := server.cmd(ctx, args); err != nil {
+		return fmt.Errorf("couldn't send command to pane: %w", err)
+	}
+
+	args = []string{
+		"-S",
+		server.SocketPath,
+		"send-keys",
+		"-t",
+		target,
+		"Enter",
+	}
+	if _, _, err := server.cmd(ctx, args); err != nil {
+		return fmt.Errorf("couldn't send command to pane: %w", err)
+	}
+	return nil
+}
+
+// atoiFields converts each of the given format fields to an int
+func atoiFields(fields ...string) ([]int, error) {
+	nums := make([]int, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return []int{}, err
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
 // SessionExists checks if session exists based on its name
-func (server *Server) SessionExists(sessionName string) bool {
+func (server *Server) SessionExists(ctx context.Context, sessionName string) bool {
 	if sessionName == "" {
 		return false
 	}
+	return server.hasSession(ctx, sessionName) == nil
+}
 
+// hasSession returns the error of `has-session`, which tells a missing session
+// apart from a missing server
+func (server *Server) hasSession(ctx context.Context, sessionName string) error {
 	// NOTE: `has-session` will either report error and exit with 1 or exit with 0
 	args := []string{
 		"-S",
 		server.SocketPath,
 		"has-session",
 		"-t",
-		sessionName,
-	}
-	_, _, err := Cmd(args)
-	if err != nil {
-		return false
+		"=" + sessionName, // NOTE: only exact matches
 	}
-	return true
+	_, _, err := server.cmd(ctx, args)
+	return err
 }
 
-// CreateSession creates a tmux session based on name and working directory
-func (server *Server) CreateSession(sessionName string, sessionPath string) (*Session, error) {
-	if sessionName == "" || strings.Contains(sessionName, ":") {
-		return &Session{}, fmt.Errorf("session names can't be empty and can't contain colons: %s", sessionName)
+// CreateSession creates a tmux session based on name and working directory. env are
+// KEY=value pairs added to the environment of the session, and so of its panes
+func (server *Server) CreateSession(ctx context.Context, sessionName string, sessionPath string, env ...string) (*Session, error) {
+	if sessionName == "" {
+		return &Session{}, errors.New("session names can't be empty")
 	}
 
-	if strings.Contains(sessionName, ".") {
-		sessionName = strings.ReplaceAll(sessionName, ".", "_")
-	}
+	sessionName = SanitizeSessionName(sessionName)
 
 	args := []string{
 		"-S",
@@ -298,44 +707,122 @@ func (server *Server) CreateSession(sessionName string, sessionPath string) (*Se
 		"-c",
 		sessionPath,
 	}
-	_, _, err := Cmd(args)
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	_, _, err := server.cmd(ctx, args)
 	if err != nil {
-		return &Session{}, err
+		return &Session{}, fmt.Errorf("couldn't create session %s: %w", sessionName, err)
 	}
 
-	session, err := server.GetSession(sessionName)
+	session, err := server.GetSession(ctx, sessionName)
 	if err != nil {
 		return &Session{}, err
 	}
 	return session, nil
 }
 
-// IsValidPath checks if a given session name is actually a valid path
-func IsValidPath(session string) bool {
-	_, err := os.Stat(session)
-	if err != nil && errors.Is(err, os.ErrNotExist) {
-		return false
+// SanitizeSessionName replaces the characters tmux doesn't allow in session names,
+// e.g. the dots of a dir name, with underscores
+func SanitizeSessionName(sessionName string) string {
+	return strings.NewReplacer(".", "_", ":", "_").Replace(sessionName)
+}
+
+// RenameSession renames a tmux session; the new name is sanitized
+func (server *Server) RenameSession(ctx context.Context, sessionName string, newName string) error {
+	if newName == "" {
+		return errors.New("session names can't be empty")
 	}
-	return true
+
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"rename-session",
+		"-t",
+		"=" + sessionName, // NOTE: only exact matches
+		SanitizeSessionName(newName),
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't rename session %s: %w", sessionName, err)
+	}
+	return nil
+}
+
+// DetachClient detaches a client from its session
+func (server *Server) DetachClient(ctx context.Context, client string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"detach-client",
+		"-t",
+		client,
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't detach client %s: %w", client, err)
+	}
+	return nil
 }
 
-// Cmd runs a tmux command with given args; returns stdout and stderr
-func Cmd(args []string) (string, string, error) {
-	tmux, err := exec.LookPath("tmux")
+// DetachSession detaches every client attached to a session
+func (server *Server) DetachSession(ctx context.Context, sessionName string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"detach-client",
+		"-s",
+		"=" + sessionName, // NOTE: only exact matches
+	}
+	_, _, err := server.cmd(ctx, args)
 	if err != nil {
-		return "", "", errors.New("couldn't find tmux in the PATH")
+		return fmt.Errorf("couldn't detach clients from session %s: %w", sessionName, err)
 	}
+	return nil
+}
+
+// KillSession kills a tmux session by name
+func (server *Server) KillSession(ctx context.Context, sessionName string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"kill-session",
+		"-t",
+		"=" + sessionName, // NOTE: only exact matches
+	}
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't kill session %s: %w", sessionName, err)
+	}
+	return nil
+}
 
-	cmd := exec.Command(tmux, args...)
+// SwitchClient switches a client to the session with the given name. An empty client
+// means the current one
+func (server *Server) SwitchClient(ctx context.Context, client string, sessionName string) error {
+	args := []string{
+		"-S",
+		server.SocketPath,
+		"switch-client",
+	}
+	if client != "" {
+		args = append(args, "-c", client)
+	}
+	// NOTE: prepending "=" to session name enforces only exact matches
+	args = append(args, "-t", "="+sessionName)
 
-	var stdout, stderr bytes.Buffer
-	// NOTE: setting stdin makes it so that creating and attach to server works
-	// but does it make sense?
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	_, _, err := server.cmd(ctx, args)
+	if err != nil {
+		return fmt.Errorf("couldn't switch client to session %s: %w", sessionName, err)
+	}
+	return nil
+}
 
-	err = cmd.Run()
-	outStr, errStr := stdout.String(), stderr.String()
-	return outStr, errStr, err
+// IsValidPath checks if a given session name is actually a valid path
+func IsValidPath(session string) bool {
+	_, err := os.Stat(session)
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	return true
 }
@@ -1,9 +1,19 @@
 package tmux
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/user"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/winter-again/flow/internal/tmux/tmuxtest"
 )
 
 func TestNewServer(t *testing.T) {
@@ -42,12 +52,529 @@ func TestNewServer(t *testing.T) {
 	}
 }
 
-func TestGetCurrentServer(t *testing.T) {}
+func TestParseWindows(t *testing.T) {
+	output := "@1;$1;0;1;b25d,80x24,0,0,1;1;80;24;editor\n@2;$1;1;0;c3a1,80x24,0,0{40x24,0,0,2,39x24,41,0,3};2;80;24;logs;tail\n"
+	windows, err := parseWindows(output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 windows but got %d", len(windows))
+	}
+	if windows[0].Index != 0 || !windows[0].Active || windows[0].Name != "editor" {
+		t.Errorf("Unexpected first window: %+v", *windows[0])
+	}
+	if windows[1].Panes != 2 || windows[1].Active || windows[1].Name != "logs;tail" {
+		t.Errorf("Unexpected second window: %+v", *windows[1])
+	}
+
+	if windows, err := parseWindows(""); err != nil || len(windows) != 0 {
+		t.Errorf("Expected no windows and no error but got %v and %v", windows, err)
+	}
+	if _, err := parseWindows("@1;$1;x;1;layout;1;80;24;editor"); err == nil {
+		t.Error("Expected error for malformed window index")
+	}
+}
 
-func TestServerStart(t *testing.T) {}
+func TestParsePanes(t *testing.T) {
+	output := "%1;@1;$1;0;1;4242;80;24;nvim;/home/user/code\n%2;@1;$1;1;0;4243;80;24;zsh;/home/user/odd;dir\n"
+	panes, err := parsePanes(output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(panes) != 2 {
+		t.Fatalf("Expected 2 panes but got %d", len(panes))
+	}
+	if panes[0].PID != 4242 || !panes[0].Active || panes[0].CurrentCommand != "nvim" || panes[0].CurrentPath != "/home/user/code" {
+		t.Errorf("Unexpected first pane: %+v", *panes[0])
+	}
+	if panes[1].Index != 1 || panes[1].CurrentPath != "/home/user/odd;dir" {
+		t.Errorf("Unexpected second pane: %+v", *panes[1])
+	}
+
+	if _, err := parsePanes("%1;@1;$1"); err == nil {
+		t.Error("Expected error for truncated pane data")
+	}
+}
+
+func TestGetCurrentServer(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/work,4242,3")
+	server, err := GetCurrentServer()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if server.SocketName != "work" || server.SocketPath != "/tmp/tmux-1000/work" {
+		t.Errorf("Expected server work at /tmp/tmux-1000/work but got %+v", *server)
+	}
+
+	t.Setenv("TMUX", "")
+	if _, err := GetCurrentServer(); !errors.Is(err, ErrNotInTmux) {
+		t.Errorf("Expected ErrNotInTmux outside tmux but got %v", err)
+	}
+}
+
+// listenUnix listens on a unix socket at path, standing in for a running server, until the test ends
+func listenUnix(t *testing.T, path string) string {
+	t.Helper()
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return path
+}
+
+// staleSocket leaves a unix socket at path that nothing listens on, like a server that crashed
+func staleSocket(t *testing.T, path string) string {
+	t.Helper()
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetUnlinkOnClose(false)
+	l.Close()
+	return path
+}
+
+func TestServerStart(t *testing.T) {
+	ctx := context.Background()
+	socketDir := t.TempDir()
+	t.Setenv("TMUX_TMPDIR", socketDir)
+	InitSessionName = "0"
+	defaultName, defaultPath := GetDefaultSocket()
+	existing := listenUnix(t, filepath.Join(socketDir, "existing"))
+	stale := staleSocket(t, filepath.Join(socketDir, "stale"))
+
+	cases := []struct {
+		name     string
+		tmuxEnv  string
+		server   Server
+		resp     tmuxtest.Response
+		wantArgs [][]string
+		wantErr  bool
+	}{
+		{
+			name:     "default socket uses name",
+			server:   Server{SocketName: defaultName, SocketPath: defaultPath},
+			wantArgs: [][]string{{"-L", defaultName, "new-session", "-d", "-s", "0"}},
+		},
+		{
+			name:     "custom socket uses path",
+			server:   Server{SocketName: "custom", SocketPath: filepath.Join(socketDir, "custom")},
+			wantArgs: [][]string{{"-S", filepath.Join(socketDir, "custom"), "new-session", "-d", "-s", "0"}},
+		},
+		{
+			name:     "nested",
+			tmuxEnv:  "/tmp/tmux-1000/default,1,0",
+			server:   Server{SocketName: "custom", SocketPath: filepath.Join(socketDir, "custom")},
+			wantArgs: [][]string{},
+			wantErr:  true,
+		},
+		{
+			name:     "socket exists",
+			server:   Server{SocketName: "existing", SocketPath: existing},
+			wantArgs: [][]string{},
+			wantErr:  true,
+		},
+		{
+			name:     "stale socket",
+			server:   Server{SocketName: "stale", SocketPath: stale},
+			wantArgs: [][]string{{"-S", stale, "new-session", "-d", "-s", "0"}},
+		},
+		{
+			name:     "tmux fails",
+			server:   Server{SocketName: "custom", SocketPath: filepath.Join(socketDir, "custom")},
+			resp:     tmuxtest.Response{Stderr: "boom", ExitCode: 1},
+			wantArgs: [][]string{{"-S", filepath.Join(socketDir, "custom"), "new-session", "-d", "-s", "0"}},
+			wantErr:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Setenv("TMUX", c.tmuxEnv)
+		fake := (&tmuxtest.Fake{}).On("new-session", c.resp)
+		c.server.Runner = fake
+
+		_, _, err := c.server.Start(ctx)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: expected error %v but got %v", c.name, c.wantErr, err)
+		}
+		if !equalCalls(fake.Calls(), c.wantArgs) {
+			t.Errorf("%s: expected calls %q but got %q", c.name, c.wantArgs, fake.Calls())
+		}
+	}
+}
+
+func TestServerAttach(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name     string
+		tmuxEnv  string
+		target   string
+		exists   int // exit code of has-session
+		wantCmds []string
+		wantErr  bool
+	}{
+		{name: "most recent session", wantCmds: []string{"attach-session"}},
+		{name: "existing target", target: "work", wantCmds: []string{"has-session", "attach-session"}},
+		{name: "missing target", target: "work", exists: 1, wantCmds: []string{"has-session"}, wantErr: true},
+		{name: "nested", tmuxEnv: "/tmp/tmux-1000/default,1,0", wantCmds: []string{}, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Setenv("TMUX", c.tmuxEnv)
+		fake := (&tmuxtest.Fake{}).On("has-session", tmuxtest.Response{ExitCode: c.exists})
+		server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+
+		_, _, err := server.Attach(ctx, c.target)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: expected error %v but got %v", c.name, c.wantErr, err)
+		}
+		if !slices.Equal(fake.Commands(), c.wantCmds) {
+			t.Errorf("%s: expected commands %v but got %v", c.name, c.wantCmds, fake.Commands())
+		}
+		calls := fake.Calls()
+		if c.target != "" && !c.wantErr {
+			want := []string{"-S", "/tmp/tmux-1000/work", "attach-session", "-t", "=" + c.target}
+			if !slices.Equal(calls[len(calls)-1], want) {
+				t.Errorf("%s: expected %q but got %q", c.name, want, calls[len(calls)-1])
+			}
+		}
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name     string
+		session  string
+		env      []string
+		create   tmuxtest.Response
+		list     string
+		wantName string
+		wantCmds []string
+		wantErr  bool
+	}{
+		{
+			name:     "created",
+			session:  "api",
+			list:     "$0;0;/home/user;1;1\n$1;api;/code/api;1;0\n",
+			wantName: "api",
+			wantCmds: []string{"new-session", "list-sessions"},
+		},
+		{
+			name:     "dots replaced",
+			session:  "example.com",
+			list:     "$1;example_com;/code/example.com;1;0\n",
+			wantName: "example_com",
+			wantCmds: []string{"new-session", "list-sessions"},
+		},
+		{
+			name:     "env",
+			session:  "api",
+			env:      []string{"PORT=8080", "MODE=dev"},
+			list:     "$1;api;/code/api;1;0\n",
+			wantName: "api",
+			wantCmds: []string{"new-session", "list-sessions"},
+		},
+		{name: "empty name", session: "", wantCmds: []string{}, wantErr: true},
+		{
+			name:     "colons replaced",
+			session:  "a:b",
+			list:     "$1;a_b;/code/api;1;0\n",
+			wantName: "a_b",
+			wantCmds: []string{"new-session", "list-sessions"},
+		},
+		{
+			name:     "tmux fails",
+			session:  "api",
+			create:   tmuxtest.Response{Stderr: "duplicate session: api", ExitCode: 1},
+			wantCmds: []string{"new-session"},
+			wantErr:  true,
+		},
+	}
+	for _, c := range cases {
+		fake := (&tmuxtest.Fake{}).
+			On("new-session", c.create).
+			On("list-sessions", tmuxtest.Response{Stdout: c.list})
+		server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+
+		session, err := server.CreateSession(ctx, c.session, "/code/api", c.env...)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: expected error %v but got %v", c.name, c.wantErr, err)
+		}
+		if !slices.Equal(fake.Commands(), c.wantCmds) {
+			t.Errorf("%s: expected commands %v but got %v", c.name, c.wantCmds, fake.Commands())
+		}
+		if c.wantErr {
+			continue
+		}
+		if session.Name != c.wantName {
+			t.Errorf("%s: expected session %s but got %+v", c.name, c.wantName, *session)
+		}
+		want := []string{"-S", "/tmp/tmux-1000/work", "new-session", "-d", "-s", c.wantName, "-c", "/code/api"}
+		for _, e := range c.env {
+			want = append(want, "-e", e)
+		}
+		if !slices.Equal(fake.Calls()[0], want) {
+			t.Errorf("%s: expected %q but got %q", c.name, want, fake.Calls()[0])
+		}
+	}
+}
+
+func TestNewWindowAndSplitWindow(t *testing.T) {
+	ctx := context.Background()
+	fake := (&tmuxtest.Fake{}).
+		On("new-window", tmuxtest.Response{}).
+		On("split-window", tmuxtest.Response{})
+	server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+
+	_, err := server.NewWindow(ctx, "$1", "logs", "/code/api")
+	if err == nil || !strings.Contains(err.Error(), "found 0") || strings.Contains(err.Error(), "%!") {
+		t.Errorf("Expected an error about finding 0 windows but got %v", err)
+	}
+	_, err = server.SplitWindow(ctx, "%1", "/code/api", true)
+	if err == nil || !strings.Contains(err.Error(), "found 0") || strings.Contains(err.Error(), "%!") {
+		t.Errorf("Expected an error about finding 0 panes but got %v", err)
+	}
+}
+
+func TestGetSessions(t *testing.T) {
+	ctx := context.Background()
+	fake := (&tmuxtest.Fake{}).On("list-sessions",
+		tmuxtest.Response{Stdout: "$0;0;/home/user;2;1\n$3;api;/code/api;1;0\n"},
+		tmuxtest.Response{Stderr: "no server running on /tmp/tmux-1000/work", ExitCode: 1},
+	)
+	server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+
+	sessions, err := server.GetSessions(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sessions) != 2 || sessions[1].Id != "$3" || sessions[1].Name != "api" || sessions[0].Windows != 2 {
+		t.Errorf("Unexpected sessions: %+v, %+v", *sessions[0], *sessions[1])
+	}
+	want := []string{"-S", "/tmp/tmux-1000/work", "list-sessions", "-F", "#{session_id};#{session_name};#{session_path};#{session_windows};#{session_attached}"}
+	if !slices.Equal(fake.Calls()[0], want) {
+		t.Errorf("Expected %q but got %q", want, fake.Calls()[0])
+	}
+
+	if _, err := server.GetSessions(ctx); err == nil {
+		t.Error("Expected error when tmux fails")
+	}
+}
+
+func TestParseSessions(t *testing.T) {
+	cases := []struct {
+		name    string
+		output  string
+		want    []Session
+		wantErr bool
+	}{
+		{
+			name:   "sessions",
+			output: "$0;0;/home/user;2;1\n$1;api;/code/api;1;0\n",
+			want: []Session{
+				{Id: "$0", Name: "0", Path: "/home/user", Windows: 2, Attached: 1},
+				{Id: "$1", Name: "api", Path: "/code/api", Windows: 1},
+			},
+		},
+		{name: "empty", output: "\n", want: []Session{}},
+		{name: "bad window count", output: "$0;0;/home/user;two;0", wantErr: true},
+		{name: "bad client count", output: "$0;0;/home/user;2;one", wantErr: true},
+		{name: "missing fields", output: "$0;0", wantErr: true},
+	}
+	for _, c := range cases {
+		sessions, err := parseSessions(c.output)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: expected error %v but got %v", c.name, c.wantErr, err)
+			continue
+		}
+		if c.wantErr {
+			continue
+		}
+		got := make([]Session, len(sessions))
+		for i, s := range sessions {
+			got[i] = *s
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: expected %+v but got %+v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name   string
+		stderr string
+		want   error
+	}{
+		{name: "no socket", stderr: "error connecting to /tmp/tmux-1000/work (No such file or directory)\n", want: ErrNoServer},
+		{name: "dead server", stderr: "no server running on /tmp/tmux-1000/work\n", want: ErrNoServer},
+		{name: "missing session", stderr: "can't find session: api\n", want: ErrSessionNotFound},
+		{name: "duplicate session", stderr: "duplicate session: api\n", want: ErrDuplicateSession},
+		{name: "other", stderr: "unknown command: nope\n", want: nil},
+	}
+	sentinels := []error{ErrNoServer, ErrSessionNotFound, ErrDuplicateSession}
+	for _, c := range cases {
+		fake := (&tmuxtest.Fake{}).On("kill-session", tmuxtest.Response{Stderr: c.stderr, ExitCode: 1})
+		server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: fake}
+
+		err := server.KillSession(ctx, "api")
+		var cmdErr *CommandError
+		if !errors.As(err, &cmdErr) {
+			t.Fatalf("%s: expected a CommandError but got %v", c.name, err)
+		}
+		if cmdErr.ExitCode != 1 || cmdErr.Stderr != strings.TrimSpace(c.stderr) || cmdErr.Args[2] != "kill-session" {
+			t.Errorf("%s: unexpected CommandError %+v", c.name, *cmdErr)
+		}
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == c.want) {
+				t.Errorf("%s: expected errors.Is(err, %v) to be %v", c.name, sentinel, !got)
+			}
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	socketPath := listenUnix(t, filepath.Join(t.TempDir(), "work"))
+	server := &Server{SocketName: "work", SocketPath: socketPath, Runner: &tmuxtest.Fake{}}
+	if _, _, err := server.Start(ctx); !errors.Is(err, ErrNested) {
+		t.Errorf("Expected ErrNested starting inside tmux but got %v", err)
+	}
+	if _, _, err := server.Attach(ctx, ""); !errors.Is(err, ErrNested) {
+		t.Errorf("Expected ErrNested attaching inside tmux but got %v", err)
+	}
+
+	t.Setenv("TMUX", "")
+	if _, _, err := server.Start(ctx); !errors.Is(err, ErrServerExists) {
+		t.Errorf("Expected ErrServerExists for an existing socket but got %v", err)
+	}
+
+	server.Runner = (&tmuxtest.Fake{}).On("has-session", tmuxtest.Response{Stderr: "can't find session: api", ExitCode: 1})
+	if _, _, err := server.Attach(ctx, "api"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound attaching to a missing session but got %v", err)
+	}
+	if _, err := server.GetSession(ctx, "api"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound getting a missing session but got %v", err)
+	}
+
+	server.Runner = (&tmuxtest.Fake{}).On("new-session", tmuxtest.Response{Stderr: "duplicate session: api", ExitCode: 1})
+	if _, err := server.CreateSession(ctx, "api", "/code/api"); !errors.Is(err, ErrDuplicateSession) {
+		t.Errorf("Expected ErrDuplicateSession but got %v", err)
+	}
+}
+
+// deadlineRunner records whether each command had a deadline and blocks until it's done
+type deadlineRunner struct {
+	deadlines map[string]bool
+}
+
+func (r *deadlineRunner) Run(ctx context.Context, args []string) (string, string, error) {
+	_, ok := ctx.Deadline()
+	r.deadlines[commandName(args)] = ok
+	if ok {
+		<-ctx.Done()
+		return "", "", ctx.Err()
+	}
+	return "", "", nil
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TMUX", "")
+	defer func(timeout time.Duration) { Timeout = timeout }(Timeout)
+	Timeout = 20 * time.Millisecond
+
+	runner := &deadlineRunner{deadlines: make(map[string]bool)}
+	server := &Server{SocketName: "work", SocketPath: "/tmp/tmux-1000/work", Runner: runner}
+
+	_, err := server.GetSessions(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded but got %v", err)
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || !strings.Contains(cmdErr.Error(), "deadline exceeded") {
+		t.Errorf("Expected CommandError mentioning the deadline but got %v", err)
+	}
+
+	// NOTE: attaching waits on the user, so only has-session is bounded
+	server.Attach(ctx, "")
+	if runner.deadlines["attach-session"] {
+		t.Error("Expected attach-session to have no deadline")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	server.Runner = &tmuxtest.Fake{}
+	if _, err := server.GetSessions(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled but got %v", err)
+	}
+}
+
+// equalCalls compares recorded command args
+func equalCalls(got [][]string, want [][]string) bool {
+	return slices.EqualFunc(got, want, func(a, b []string) bool { return slices.Equal(a, b) })
+}
 
 // For trying stuff
-func TestMain(t *testing.T) {
-	t.Logf("User ID: %s", getUID())
-	t.Log(user.Current())
+func TestGetUID(t *testing.T) {
+	// NOTE: tmux names its socket dir after the real user ID
+	if got, want := getUID(), strconv.Itoa(os.Getuid()); got != want {
+		t.Errorf("Expected UID %s but got %s", want, got)
+	}
+}
+
+func TestSanitizeSessionName(t *testing.T) {
+	cases := map[string]string{
+		"api":         "api",
+		"example.com": "example_com",
+		"a:b.c":       "a_b_c",
+	}
+	for in, want := range cases {
+		if got := SanitizeSessionName(in); got != want {
+			t.Errorf("SanitizeSessionName(%q): expected %q but got %q", in, want, got)
+		}
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	ctx := context.Background()
+	socketPath := "/tmp/tmux-1000/work"
+	cases := []struct {
+		name     string
+		run      func(server *Server) error
+		wantArgs []string
+	}{
+		{
+			name:     "rename",
+			run:      func(server *Server) error { return server.RenameSession(ctx, "api", "api.v2") },
+			wantArgs: []string{"-S", socketPath, "rename-session", "-t", "=api", "api_v2"},
+		},
+		{
+			name:     "detach client",
+			run:      func(server *Server) error { return server.DetachClient(ctx, "/dev/pts/1") },
+			wantArgs: []string{"-S", socketPath, "detach-client", "-t", "/dev/pts/1"},
+		},
+		{
+			name:     "detach session",
+			run:      func(server *Server) error { return server.DetachSession(ctx, "api") },
+			wantArgs: []string{"-S", socketPath, "detach-client", "-s", "=api"},
+		},
+	}
+	for _, c := range cases {
+		fake := &tmuxtest.Fake{}
+		if err := c.run(&Server{SocketName: "work", SocketPath: socketPath, Runner: fake}); err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if !equalCalls(fake.Calls(), [][]string{c.wantArgs}) {
+			t.Errorf("%s: expected %q but got %q", c.name, c.wantArgs, fake.Calls())
+		}
+	}
+
+	server := &Server{SocketName: "work", SocketPath: socketPath, Runner: &tmuxtest.Fake{}}
+	if err := server.RenameSession(ctx, "api", ""); err == nil {
+		t.Error("Expected error renaming to an empty name")
+	}
 }
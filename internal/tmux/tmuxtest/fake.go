@@ -0,0 +1,105 @@
+// Package tmuxtest provides a fake tmux.Runner for testing code that talks to tmux
+package tmuxtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Response is the canned result of a tmux command
+type Response struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// ExitError is returned for responses with a non-zero exit code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode matches exec.ExitError so that tmux reports the code like for real failures
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Fake records the args of every command it runs and answers with responses scripted
+// per tmux command. Commands without a script succeed with no output
+type Fake struct {
+	mu        sync.Mutex
+	calls     [][]string
+	responses map[string][]Response
+}
+
+// On scripts the responses to a tmux command such as "list-sessions". Each call
+// consumes the next response and the last one is repeated
+func (f *Fake) On(command string, responses ...Response) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.responses == nil {
+		f.responses = make(map[string][]Response)
+	}
+	f.responses[command] = append(f.responses[command], responses...)
+	return f
+}
+
+func (f *Fake) Run(ctx context.Context, args []string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, append([]string{}, args...))
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	command := Command(args)
+	queued := f.responses[command]
+	if len(queued) == 0 {
+		return "", "", nil
+	}
+	resp := queued[0]
+	if len(queued) > 1 {
+		f.responses[command] = queued[1:]
+	}
+
+	if resp.ExitCode != 0 {
+		return resp.Stdout, resp.Stderr, &ExitError{Code: resp.ExitCode}
+	}
+	return resp.Stdout, resp.Stderr, nil
+}
+
+// Calls returns the args of every command run so far
+func (f *Fake) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string{}, f.calls...)
+}
+
+// Commands returns the tmux command of every call run so far, e.g. "new-session"
+func (f *Fake) Commands() []string {
+	calls := f.Calls()
+	commands := make([]string, len(calls))
+	for i, args := range calls {
+		commands[i] = Command(args)
+	}
+	return commands
+}
+
+// Command returns the tmux command in args, skipping the global flags before it
+func Command(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-S" || args[i] == "-L" || args[i] == "-f":
+			i++ // NOTE: these take a value
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			return args[i]
+		}
+	}
+	return ""
+}
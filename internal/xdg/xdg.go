@@ -0,0 +1,36 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigHome returns $XDG_CONFIG_HOME, falling back to ~/.config
+func ConfigHome() (string, error) {
+	return baseDir("XDG_CONFIG_HOME", ".config")
+}
+
+// StateHome returns $XDG_STATE_HOME, falling back to ~/.local/state
+func StateHome() (string, error) {
+	return baseDir("XDG_STATE_HOME", ".local/state")
+}
+
+// CacheHome returns $XDG_CACHE_HOME, falling back to ~/.cache
+func CacheHome() (string, error) {
+	return baseDir("XDG_CACHE_HOME", ".cache")
+}
+
+// baseDir returns the directory in the given environment var if it's an absolute path,
+// otherwise the fallback relative to the user's home directory
+func baseDir(envVar string, fallback string) (string, error) {
+	// NOTE: the spec says relative paths are invalid and should be ignored
+	if dir := os.Getenv(envVar); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback), nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func Kill() *cli.Command {
+	var allButCurrent, detached, dryRun bool
+	var pattern string
+
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "kill",
+		Usage:                  "Kill the given sessions, or the sessions matching all of the filters",
+		ArgsUsage:              "[session...]",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "all-but-current",
+				Usage:       "Kill every session but the one flow runs in",
+				Destination: &allButCurrent,
+			},
+			&cli.BoolFlag{
+				Name:        "detached",
+				Aliases:     []string{"d"},
+				Usage:       "Only kill sessions no client is attached to",
+				Destination: &detached,
+			},
+			&cli.StringFlag{
+				Name:        "pattern",
+				Usage:       "Only kill sessions with names matching a glob, e.g. 'scratch*'",
+				Destination: &pattern,
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Usage:       "Print the sessions that would be killed without killing them",
+				Destination: &dryRun,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			names := cmd.Args().Slice()
+			if len(names) == 0 && !allButCurrent && !detached && pattern == "" {
+				return cli.Exit("expected session names or a filter: --all-but-current, --detached or --pattern", 1)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return cli.Exit(fmt.Sprintf("invalid pattern %q: %v", pattern, err), 1)
+			}
+			server := targetServer(cmd, socketName, socketPath)
+
+			sessions, err := server.GetSessions(ctx)
+			if err != nil {
+				return exitError("error while listing sessions", err)
+			}
+			current, err := currentOn(ctx, server)
+			if err != nil {
+				return exitError("error while finding current session", err)
+			}
+			if allButCurrent && current == nil {
+				return cli.Exit("--all-but-current only works inside tmux, on the same server", 1)
+			}
+
+			for _, name := range names {
+				if !slices.ContainsFunc(sessions, func(s *tmux.Session) bool { return s.Name == name }) {
+					return exitError("error while killing sessions", fmt.Errorf("%w: %s", tmux.ErrSessionNotFound, name))
+				}
+			}
+
+			var targets []*tmux.Session
+			for _, session := range sessions {
+				switch {
+				case len(names) > 0 && !slices.Contains(names, session.Name):
+				case allButCurrent && session.Id == current.SessionId:
+				case detached && session.Attached > 0:
+				case pattern != "" && !matchName(pattern, session.Name):
+				default:
+					targets = append(targets, session)
+				}
+			}
+			// NOTE: killing the session flow runs in takes flow down with it, so do it last
+			if current != nil {
+				slices.SortStableFunc(targets, func(a, b *tmux.Session) int {
+					if a.Id == current.SessionId {
+						return 1
+					}
+					if b.Id == current.SessionId {
+						return -1
+					}
+					return 0
+				})
+			}
+
+			for _, session := range targets {
+				fmt.Println(session.Name)
+				if dryRun {
+					continue
+				}
+				if err := server.KillSession(ctx, session.Name); err != nil {
+					return exitError("error while killing sessions", err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// matchName reports whether a session name matches a glob
+func matchName(pattern string, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func Last() *cli.Command {
+	var n int
+
+	return &cli.Command{
+		Name:  "last",
+		Usage: "Switch to the session visited before the current one, recreating it from its dir if it's gone",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:        "n",
+				Value:       1,
+				Usage:       "How far back to go: 1 is the previous session, 2 the one before it and so on",
+				Destination: &n,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if n < 1 {
+				return cli.Exit("--n must be at least 1", 1)
+			}
+
+			server, err := tmux.GetCurrentServer()
+			if err != nil {
+				return exitError("error while finding current server", err)
+			}
+			current, err := server.GetCurrent(ctx)
+			if err != nil {
+				return exitError("error while finding current client", err)
+			}
+			sessions, err := server.GetSessions(ctx)
+			if err != nil {
+				return exitError("error while listing sessions", err)
+			}
+
+			store, err := loadHistory()
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			// NOTE: the current session may have been reached without flow, so it goes
+			// on top before looking back from it
+			from := currentVisit(server, current, sessions)
+			if from != nil {
+				store.Push(*from)
+			}
+			visit, ok := store.Previous(server.SocketPath, n)
+			if !ok {
+				return cli.Exit(fmt.Sprintf("flow doesn't remember %d sessions before this one", n), 1)
+			}
+
+			var session *tmux.Session
+			createdFrom := ""
+			if i := slices.IndexFunc(sessions, func(s *tmux.Session) bool { return s.Name == visit.Session }); i >= 0 {
+				session = sessions[i]
+			} else {
+				if visit.Path == "" {
+					return cli.Exit(fmt.Sprintf("session %s is gone and flow doesn't know its dir to recreate it", visit.Session), 1)
+				}
+				project, err := projectFor(visit.Path)
+				if err != nil {
+					return exitError("error while reading project", err)
+				}
+				session, err = createSession(ctx, server, visit.Session, visit.Path, project)
+				if err != nil {
+					return exitError("error while recreating session", err)
+				}
+				createdFrom = visit.Path
+			}
+
+			if err := switchSess(ctx, server, current.Client, session); err != nil {
+				return exitError("error while switching sessions", err)
+			}
+			recordVisit(from, visitTo(server, session), createdFrom)
+			runSwitchHooks(ctx, session)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+	"golang.org/x/term"
+)
+
+func List() *cli.Command {
+	var windows, panes, watch bool
+	var format, tmpl string
+
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "list",
+		Aliases:                []string{"ls"},
+		Usage:                  "List the sessions, windows or panes of a tmux server",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "windows",
+				Aliases:     []string{"w"},
+				Usage:       "List windows instead of sessions",
+				Destination: &windows,
+			},
+			&cli.BoolFlag{
+				Name:        "panes",
+				Usage:       "List panes instead of sessions",
+				Destination: &panes,
+			},
+			&cli.StringFlag{
+				Name:        "format",
+				Aliases:     []string{"f"},
+				Value:       "table",
+				Usage:       "Output format: table, json or tsv",
+				Destination: &format,
+			},
+			&cli.BoolFlag{
+				Name:        "watch",
+				Usage:       "Keep listing whenever the server changes, until <ctrl-c>",
+				Destination: &watch,
+			},
+			&cli.StringFlag{
+				Name:        "template",
+				Aliases:     []string{"t"},
+				Usage:       "Go template executed for each item, e.g. '{{.Name}} {{.Windows}}'. Overrides --format.",
+				Destination: &tmpl,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			server := flagServer(cmd, socketName, socketPath)
+
+			var last string // what was last listed while watching
+			list := func(ctx context.Context, server *tmux.Server) error {
+				var l listing
+				var err error
+				switch {
+				case panes:
+					l, err = listPanes(ctx, server)
+				case windows:
+					l, err = listWindows(ctx, server)
+				default:
+					l, err = listSessions(ctx, server)
+				}
+				if err != nil {
+					return exitError(fmt.Sprintf("error while listing server with socket path '%s'", server.SocketPath), err)
+				}
+
+				var out bytes.Buffer
+				if tmpl != "" {
+					err = l.writeTemplate(&out, tmpl)
+				} else {
+					err = l.write(&out, format)
+				}
+				if err != nil {
+					return cli.Exit(err, 1)
+				}
+
+				// NOTE: not every change in the server shows up in the listing
+				if watch && out.String() == last {
+					return nil
+				}
+				last = out.String()
+				if watch && tmpl == "" && format == "table" && term.IsTerminal(int(os.Stdout.Fd())) {
+					fmt.Print("\033[H\033[2J") // NOTE: clear the screen
+				}
+				_, err = out.WriteTo(os.Stdout)
+				return err
+			}
+
+			if !watch {
+				return list(ctx, server)
+			}
+			return watchServer(ctx, server, list)
+		},
+	}
+}
+
+// watchDebounce is how long to wait for more changes before listing again
+const watchDebounce = 50 * time.Millisecond
+
+// watchServer calls list, then calls it again every time the server changes until
+// ctx is done. Queries go through a control client, which also reports the changes
+func watchServer(ctx context.Context, server *tmux.Server, list func(context.Context, *tmux.Server) error) error {
+	for {
+		ctrl, err := server.Connect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return exitError(fmt.Sprintf("error while connecting to server with socket path '%s'", server.SocketPath), err)
+		}
+
+		conn := *server
+		conn.Runner = ctrl
+		err = watchControl(ctx, &conn, ctrl, list)
+		ctrl.Close()
+		if ctx.Err() != nil {
+			// NOTE: <ctrl-c> is how watching is meant to end
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// NOTE: the client exits when its session is killed, so connect again
+	}
+}
+
+// watchControl lists until ctx is done or the control client exits
+func watchControl(ctx context.Context, server *tmux.Server, ctrl *tmux.Control, list func(context.Context, *tmux.Server) error) error {
+	for {
+		if err := list(ctx, server); err != nil {
+			if ctrl.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case _, ok := <-ctrl.Notifications():
+			if !ok {
+				return nil
+			}
+		case <-ctx.Done():
+			return nil
+		}
+
+		// NOTE: changes come in bursts, e.g. killing a session closes its windows
+		// too, so list once per burst
+		debounce := time.After(watchDebounce)
+	burst:
+		for {
+			select {
+			case _, ok := <-ctrl.Notifications():
+				if !ok {
+					return nil
+				}
+			case <-debounce:
+				break burst
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// listing is what `flow list` prints: items for json and templates, and the same
+// items as columns for table and tsv
+type listing struct {
+	header []string
+	items  []any
+	rows   [][]string
+}
+
+func (l *listing) add(item any, row ...string) {
+	l.items = append(l.items, item)
+	l.rows = append(l.rows, row)
+}
+
+func (l *listing) write(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(l.header, "\t"))
+		for _, row := range l.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "tsv":
+		// NOTE: no header so that the output can go straight into cut or awk
+		for _, row := range l.rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if l.items == nil {
+			return enc.Encode([]any{})
+		}
+		return enc.Encode(l.items)
+	default:
+		return fmt.Errorf("unknown format %q: expected one of table, json or tsv", format)
+	}
+}
+
+func (l *listing) writeTemplate(w io.Writer, text string) error {
+	t, err := template.New("list").Parse(text)
+	if err != nil {
+		return fmt.Errorf("couldn't parse template: %w", err)
+	}
+	for _, item := range l.items {
+		if err := t.Execute(w, item); err != nil {
+			return fmt.Errorf("couldn't execute template: %w", err)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+type sessionItem struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Windows int    `json:"windows"`
+}
+
+type windowItem struct {
+	Id      string `json:"id"`
+	Session string `json:"session"`
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	Active  bool   `json:"active"`
+	Panes   int    `json:"panes"`
+	Layout  string `json:"layout"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+type paneItem struct {
+	Id      string `json:"id"`
+	Session string `json:"session"`
+	Window  int    `json:"window"` // index of the window
+	Index   int    `json:"index"`
+	Active  bool   `json:"active"`
+	Command string `json:"command"`
+	Path    string `json:"path"`
+	PID     int    `json:"pid"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+func listSessions(ctx context.Context, server *tmux.Server) (listing, error) {
+	sessions, err := server.GetSessions(ctx)
+	if err != nil {
+		return listing{}, err
+	}
+
+	l := listing{header: []string{"ID", "NAME", "WINDOWS", "PATH"}}
+	for _, s := range sessions {
+		l.add(sessionItem{Id: s.Id, Name: s.Name, Path: s.Path, Windows: s.Windows},
+			s.Id, s.Name, strconv.Itoa(s.Windows), s.Path)
+	}
+	return l, nil
+}
+
+func listWindows(ctx context.Context, server *tmux.Server) (listing, error) {
+	windows, err := server.GetWindows(ctx, nil)
+	if err != nil {
+		return listing{}, err
+	}
+
+	l := listing{header: []string{"ID", "SESSION", "INDEX", "NAME", "ACTIVE", "PANES", "SIZE"}}
+	for _, w := range windows {
+		l.add(windowItem{
+			Id:      w.Id,
+			Session: w.SessionName,
+			Index:   w.Index,
+			Name:    w.Name,
+			Active:  w.Active,
+			Panes:   w.Panes,
+			Layout:  w.Layout,
+			Width:   w.Width,
+			Height:  w.Height,
+		}, w.Id, w.SessionName, strconv.Itoa(w.Index), w.Name, strconv.FormatBool(w.Active),
+			strconv.Itoa(w.Panes), fmt.Sprintf("%dx%d", w.Width, w.Height))
+	}
+	return l, nil
+}
+
+func listPanes(ctx context.Context, server *tmux.Server) (listing, error) {
+	// NOTE: panes only know the IDs of their window and session, so look up the
+	// names and indexes users actually recognize
+	windows, err := server.GetWindows(ctx, nil)
+	if err != nil {
+		return listing{}, err
+	}
+	windowsById := make(map[string]*tmux.Window, len(windows))
+	for _, w := range windows {
+		windowsById[w.Id] = w
+	}
+
+	panes, err := server.GetPanes(ctx, nil)
+	if err != nil {
+		return listing{}, err
+	}
+
+	l := listing{header: []string{"ID", "SESSION", "WINDOW", "INDEX", "ACTIVE", "COMMAND", "PATH"}}
+	for _, p := range panes {
+		item := paneItem{
+			Id:      p.Id,
+			Index:   p.Index,
+			Active:  p.Active,
+			Command: p.CurrentCommand,
+			Path:    p.CurrentPath,
+			PID:     p.PID,
+			Width:   p.Width,
+			Height:  p.Height,
+		}
+		if w, ok := windowsById[p.WindowId]; ok {
+			item.Session = w.SessionName
+			item.Window = w.Index
+		}
+		l.add(item, p.Id, item.Session, strconv.Itoa(item.Window), strconv.Itoa(p.Index),
+			strconv.FormatBool(p.Active), p.CurrentCommand, p.CurrentPath)
+	}
+	return l, nil
+}
@@ -4,11 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 
-	"github.com/knadh/koanf/parsers/toml"
-	"github.com/knadh/koanf/providers/confmap"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/v2"
 	"github.com/urfave/cli/v3"
 
@@ -19,56 +17,71 @@ var k = koanf.New(".")
 
 func main() {
 	// TODO: create global --debug flag for logging?
-	if err := loadConfig(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
 
-	// NOTE: is this any better than rereading the config file in that package?
-	tmux.InitSessionName = k.String("flow.init_session_name")
+	// NOTE: <ctrl-c> cancels in-flight tmux commands and dir walks; a second one
+	// kills flow right away since the handler is removed after the first
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
 
 	cmd := &cli.Command{
 		Name:    "flow",
 		Version: "v0.1.3",
 		Usage:   "CLI for managing tmux sessions",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Aliases: []string{"c"},
+				Usage:   "Config file to load instead of $XDG_CONFIG_HOME/flow/config.toml",
+				Sources: cli.EnvVars("FLOW_CONFIG"),
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Usage:   "Profile of the config to use, e.g. work for [profile.work]",
+				Sources: cli.EnvVars("FLOW_PROFILE"),
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			// NOTE: `flow config` loads the config itself, since it has to work on broken configs.
+			// `flow pick` needs no settings, and runs in a popup that has the tmux server's env
+			// rather than the caller's, so it would load the wrong config or profile
+			switch cmd.Args().First() {
+			case "config", "pick":
+				return ctx, nil
+			}
+			if err := loadConfig(cmd.String("config"), cmd.String("profile")); err != nil {
+				return ctx, cli.Exit(err, 1)
+			}
+			if err := validateConfig(); err != nil {
+				return ctx, cli.Exit(fmt.Sprintf("invalid config; see `flow config validate`:\n%v", err), 1)
+			}
+
+			// NOTE: is this any better than rereading the config file in that package?
+			tmux.InitSessionName = k.String("flow.init_session_name")
+			tmux.Timeout = k.Duration("flow.timeout")
+			return ctx, nil
+		},
 		Commands: []*cli.Command{
 			Start(),
 			Attach(),
+			New(),
+			Kill(),
+			Rename(),
+			Detach(),
 			Switch(),
+			Last(),
 			Find(),
+			List(),
+			Servers(),
+			Config(),
+			Save(),
+			Restore(),
+			Pick(),
 		},
 	}
 
-	if err := cmd.Run(context.Background(), os.Args); err != nil {
+	if err := cmd.Run(ctx, os.Args); err != nil {
 		fmt.Println(err)
 		os.Exit(1) // NOTE: this might be redundant?
 	}
 }
-
-func loadConfig() error {
-	// TODO: should allow user to config this from fzf-tmux instead?
-	k.Load(confmap.Provider(map[string]any{
-		"flow.init_session_name":   "0",
-		"fzf-tmux.length":          "60%",
-		"fzf-tmux.width":           "80%",
-		"fzf-tmux.border":          "rounded",
-		"fzf-tmux.preview_size":    "60%",
-		"fzf-tmux.preview_border":  "rounded",
-		"fzf-tmux.preview_dir_cmd": []string{"ls"},
-		"fzf-tmux.preview_pos":     "right",
-		// TODO: check if $HOME can be used
-		"find.dirs": []string{"$HOME"},
-	}, "."), nil)
-
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
-	// TODO: what about --config flag for custom loc?
-	config := filepath.Join(home, ".config/flow/config.toml")
-	if err := k.Load(file.Provider(config), toml.Parser()); err != nil {
-		return fmt.Errorf("error loading config file: %w", err)
-	}
-	return nil
-}
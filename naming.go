@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+
+	"github.com/winter-again/flow/internal/layout"
+	"github.com/winter-again/flow/internal/naming"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// namingOptions returns how session names are derived from dirs, from the config
+func namingOptions() naming.Options {
+	return naming.Options{
+		Strategy: k.String("naming.strategy"),
+		Template: k.String("naming.template"),
+		Remote:   k.String("naming.remote"),
+	}
+}
+
+// dirName returns the name set by the project of a dir, if any, or else the name the
+// naming strategy derives from the dir
+func dirName(ctx context.Context, dir string, project *layout.Project) (string, error) {
+	if project != nil && project.Name != "" {
+		return tmux.SanitizeSessionName(project.Name), nil
+	}
+	return naming.Name(ctx, dir, namingOptions())
+}
+
+// nameForDir derives the name of a new session for a dir, made unique among the sessions
+func nameForDir(ctx context.Context, sessions []*tmux.Session, dir string, project *layout.Project) (string, error) {
+	name, err := dirName(ctx, dir, project)
+	if err != nil {
+		return "", err
+	}
+	return uniqueName(sessions, name, dir), nil
+}
+
+// sessionForDir returns the session whose working dir is dir, preferring the one with
+// the name flow would give it, or else a session to create there with a unique name.
+// Reports whether the session already exists
+func sessionForDir(ctx context.Context, sessions []*tmux.Session, dir string, project *layout.Project) (*tmux.Session, bool, error) {
+	dir = filepath.Clean(dir)
+	name, err := dirName(ctx, dir, project)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var match *tmux.Session
+	for _, session := range sessions {
+		if filepath.Clean(session.Path) != dir {
+			continue
+		}
+		if session.Name == name {
+			return session, true, nil
+		}
+		if match == nil {
+			match = session
+		}
+	}
+	if match != nil {
+		return match, true, nil
+	}
+	return &tmux.Session{Name: uniqueName(sessions, name, dir), Path: dir}, false, nil
+}
+
+// uniqueName disambiguates the name of a new session for a dir from the sessions' names
+func uniqueName(sessions []*tmux.Session, name string, dir string) string {
+	return naming.Unique(name, dir, func(name string) bool {
+		return slices.ContainsFunc(sessions, func(s *tmux.Session) bool { return s.Name == name })
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/finder"
+	"github.com/winter-again/flow/internal/layout"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func New() *cli.Command {
+	var sessionName string
+	var detached bool
+
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "new",
+		Usage:                  "Create a session from a dir, or with a name in the current dir, and go to it",
+		ArgsUsage:              "<dir|name>",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "session",
+				Aliases:     []string{"s"},
+				Usage:       "Name of the session. Defaults to the name of the dir.",
+				Destination: &sessionName,
+			},
+			&cli.BoolFlag{
+				Name:        "detached",
+				Aliases:     []string{"d"},
+				Usage:       "Don't switch or attach to the session",
+				Destination: &detached,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.NArg() != 1 {
+				return cli.Exit("expected a dir or session name", 1)
+			}
+			server := targetServer(cmd, socketName, socketPath)
+
+			name, dir, err := newSessionTarget(cmd.Args().First())
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			var project *layout.Project
+			if dir != "" {
+				if project, err = projectFor(dir); err != nil {
+					return exitError("error while reading project", err)
+				}
+			}
+			if sessionName != "" {
+				name = sessionName
+			} else if dir != "" {
+				// NOTE: new-session starts the server if it isn't running, so there may be no sessions yet
+				sessions, err := server.GetSessions(ctx)
+				if err != nil && !errors.Is(err, tmux.ErrNoServer) {
+					return exitError("error while listing sessions", err)
+				}
+				if name, err = nameForDir(ctx, sessions, dir, project); err != nil {
+					return exitError("error while naming session", err)
+				}
+			}
+			path := dir
+			if path == "" {
+				if path, err = os.Getwd(); err != nil {
+					return cli.Exit(fmt.Sprintf("error while finding current dir: %v", err), 1)
+				}
+			}
+
+			session, err := createSession(ctx, server, tmux.SanitizeSessionName(name), path, project)
+			if err != nil {
+				return exitError("error while creating session", err)
+			}
+			if detached {
+				return nil
+			}
+			from := sessionVisit(ctx)
+			if err := goToSession(ctx, server, session.Name); err != nil {
+				return exitError("error while going to session", err)
+			}
+			recordVisit(from, visitTo(server, session), dir)
+			runSwitchHooks(ctx, session)
+			return nil
+		},
+	}
+}
+
+// newSessionTarget interprets the arg of `flow new` as either a dir, left unnamed for
+// nameForDir, or the name of a session; returns the name or the dir
+func newSessionTarget(arg string) (string, string, error) {
+	path := finder.ExpandPath(arg)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		dir, err := filepath.Abs(path)
+		if err != nil {
+			return "", "", err
+		}
+		return "", dir, nil
+	}
+	// NOTE: anything that looks like a path is meant as one, so don't name a session after it
+	if strings.ContainsRune(arg, filepath.Separator) {
+		return "", "", fmt.Errorf("no such dir: %s", arg)
+	}
+	return arg, "", nil
+}
+
+// goToSession switches the client flow runs in to the session when inside tmux, moving
+// it over if the session is on another server, or else attaches to the session
+func goToSession(ctx context.Context, server *tmux.Server, sessionName string) error {
+	current, err := tmux.GetCurrentServer()
+	if errors.Is(err, tmux.ErrNotInTmux) {
+		_, _, err := server.Attach(ctx, sessionName)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	here, err := current.GetCurrent(ctx)
+	if err != nil {
+		return err
+	}
+	if current.SocketPath != server.SocketPath {
+		return current.MoveClient(ctx, here.Client, server, sessionName)
+	}
+	return current.SwitchClient(ctx, here.Client, sessionName)
+}
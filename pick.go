@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/picker"
+)
+
+// exitCancelled matches the exit code fzf uses for <esc> and <ctrl-c>
+const exitCancelled = 130
+
+func Pick() *cli.Command {
+	var itemsFile, outputFile string
+	var opts picker.Options
+
+	return &cli.Command{
+		Name:   "pick",
+		Usage:  "Run the builtin picker; used by switch inside a tmux popup",
+		Hidden: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "items",
+				Usage:       "File with one item per line",
+				Required:    true,
+				Destination: &itemsFile,
+			},
+			&cli.StringFlag{
+				Name:        "output",
+				Usage:       "File the action and selection are written to",
+				Required:    true,
+				Destination: &outputFile,
+			},
+			&cli.StringFlag{
+				Name:        "prompt",
+				Destination: &opts.Prompt,
+			},
+			&cli.StringFlag{
+				Name:        "header",
+				Destination: &opts.Header,
+			},
+			&cli.StringFlag{
+				Name:        "preview",
+				Usage:       "Preview command; {} is replaced by the highlighted item",
+				Destination: &opts.Preview,
+			},
+			&cli.StringFlag{
+				Name:        "preview-pos",
+				Value:       "right",
+				Destination: &opts.PreviewPos,
+			},
+			&cli.IntFlag{
+				Name:        "preview-size",
+				Value:       50,
+				Usage:       "Percent of the popup used by the preview",
+				Destination: &opts.PreviewSize,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			data, err := os.ReadFile(itemsFile)
+			if err != nil {
+				return cli.Exit(fmt.Errorf("error reading picker items: %w", err), 1)
+			}
+			var items []string
+			if s := strings.TrimRight(string(data), "\n"); s != "" {
+				items = strings.Split(s, "\n")
+			}
+
+			result, err := picker.Run(ctx, os.Stdin, items, opts)
+			if err != nil {
+				if errors.Is(err, picker.ErrCancelled) {
+					return cli.Exit("", exitCancelled)
+				}
+				return cli.Exit(err, 1)
+			}
+
+			if err := os.WriteFile(outputFile, []byte(picker.FormatResult(result)), 0o600); err != nil {
+				return cli.Exit(fmt.Errorf("error writing picker result: %w", err), 1)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+
+	"github.com/winter-again/flow/internal/config"
+	"github.com/winter-again/flow/internal/finder"
+	"github.com/winter-again/flow/internal/layout"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+// projectFor returns the project of a dir: the first [[project]] of the config matching
+// it, merged with the project files of the dir and its parents up to its find root, inner
+// ones last. Project files of dirs not in flow.trust are skipped with a warning. Returns
+// nil if there's no project
+func projectFor(dir string) (*layout.Project, error) {
+	var projects []layout.Project
+	if err := k.Unmarshal("project", &projects); err != nil {
+		return nil, fmt.Errorf("error reading projects from config: %w", err)
+	}
+
+	var project *layout.Project
+	if match, ok := layout.Match(projects, dir); ok {
+		project = match
+	}
+	for _, path := range layout.FindFiles(dir, findRootOf(dir)) {
+		if !trusted(filepath.Dir(path)) {
+			warn(fmt.Errorf("skipping %s since its dir isn't in flow.trust", path))
+			continue
+		}
+		over, err := loadProjectFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if project == nil {
+			project = &layout.Project{}
+		}
+		project.Merge(over)
+	}
+	return project, nil
+}
+
+// findRootOf returns the deepest find root holding dir, or dir itself if none does
+func findRootOf(dir string) string {
+	roots, err := findRoots()
+	if err != nil {
+		return dir
+	}
+
+	best := dir
+	depth := -1
+	for _, root := range roots {
+		path := filepath.Clean(finder.ExpandPath(root.Path))
+		if !isWithin(dir, path) {
+			continue
+		}
+		if n := strings.Count(path, string(filepath.Separator)); n > depth {
+			best, depth = path, n
+		}
+	}
+	return best
+}
+
+// trusted reports whether a dir, or one of its parents, is one of the dirs or matches one
+// of the globs in flow.trust
+func trusted(dir string) bool {
+	for _, pattern := range k.Strings("flow.trust") {
+		pattern = filepath.Clean(finder.ExpandPath(pattern))
+		for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+			if d == pattern {
+				return true
+			}
+			if ok, err := filepath.Match(pattern, d); err == nil && ok {
+				return true
+			}
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// isWithin reports whether dir is root or below it
+func isWithin(dir string, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// loadProjectFile reads and checks a project file, pointing problems at their lines
+func loadProjectFile(path string) (*layout.Project, error) {
+	pk := koanf.New(".")
+	if err := pk.Load(file.Provider(path), toml.Parser()); err != nil {
+		return nil, fmt.Errorf("error loading project file %s: %w", path, err)
+	}
+
+	project, errs := config.LoadProject(pk)
+	if len(errs) > 0 {
+		lines, _ := config.Lines(path)
+		for _, err := range errs {
+			err.Source = fileSource(path, err.Key, lines)
+		}
+		return nil, fmt.Errorf("invalid project file:\n%w", errs)
+	}
+	return project, nil
+}
+
+// createSession creates a session in dir for its project, if it has one: runs the create
+// hooks, then creates the session with the project's env and builds its windows
+func createSession(ctx context.Context, server *tmux.Server, name string, dir string, project *layout.Project) (*tmux.Session, error) {
+	if project == nil {
+		return server.CreateSession(ctx, name, dir)
+	}
+
+	if err := runHooks(ctx, project.Hooks.Create, name, dir, project); err != nil {
+		return nil, err
+	}
+	session, err := server.CreateSession(ctx, name, dir, project.EnvList()...)
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.Apply(ctx, server, session, project); err != nil {
+		return nil, fmt.Errorf("error applying layout to session %s: %w", session.Name, err)
+	}
+	return session, nil
+}
+
+// runSwitchHooks runs the switch hooks of the project of the session flow went to.
+// Hooks only cost what they were meant to do, so problems are reported as warnings
+func runSwitchHooks(ctx context.Context, session *tmux.Session) {
+	if session.Path == "" {
+		return
+	}
+	project, err := projectFor(session.Path)
+	if err != nil {
+		warn(err)
+		return
+	}
+	if project == nil {
+		return
+	}
+	if err := runHooks(ctx, project.Hooks.Switch, session.Name, session.Path, project); err != nil {
+		warn(err)
+	}
+}
+
+// runHooks runs hook commands one by one with sh in the session's dir, with the project's
+// env along with $FLOW_SESSION and $FLOW_DIR. Stops at the first one that fails
+func runHooks(ctx context.Context, hooks []string, name string, dir string, project *layout.Project) error {
+	env := append(os.Environ(), project.EnvList()...)
+	env = append(env, "FLOW_SESSION="+name, "FLOW_DIR="+dir)
+
+	for _, hook := range hooks {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", hook)
+		cmd.Dir, cmd.Env, cmd.Stderr = dir, env, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("hook %q failed: %w: %s", hook, err, msg)
+			}
+			return fmt.Errorf("hook %q failed: %w", hook, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func Rename() *cli.Command {
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "rename",
+		Usage:                  "Rename a session, or the one flow runs in if only the new name is given",
+		ArgsUsage:              "[session] <new name>",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			server := targetServer(cmd, socketName, socketPath)
+
+			var sessionName, newName string
+			switch cmd.NArg() {
+			case 1:
+				current, err := currentOn(ctx, server)
+				if err != nil {
+					return exitError("error while finding current session", err)
+				}
+				if current == nil {
+					return cli.Exit("expected the session to rename since flow isn't inside tmux, on the same server", 1)
+				}
+				sessionName, newName = current.SessionName, cmd.Args().Get(0)
+			case 2:
+				sessionName, newName = cmd.Args().Get(0), cmd.Args().Get(1)
+			default:
+				return cli.Exit("expected the new name, and optionally the session to rename before it", 1)
+			}
+
+			if err := server.RenameSession(ctx, sessionName, newName); err != nil {
+				return exitError("error while renaming session", err)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/snapshot"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func Restore() *cli.Command {
+	var file string
+
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "restore",
+		Usage:                  "Recreate sessions from a saved snapshot",
+		ArgsUsage:              "[session...]",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "file",
+				Aliases:     []string{"f"},
+				Usage:       "Snapshot file to restore. Defaults to the most recent snapshot.",
+				Destination: &file,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			server := flagServer(cmd, socketName, socketPath)
+
+			if file == "" {
+				dir, err := snapshotDir()
+				if err != nil {
+					return cli.Exit(err, 1)
+				}
+				file, err = snapshot.Latest(dir)
+				if err != nil {
+					return cli.Exit(fmt.Errorf("error while finding snapshot in %s: %w", dir, err), 1)
+				}
+			}
+
+			snap, err := snapshot.Load(file)
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+
+			restored, err := snapshot.Restore(ctx, server, snap, cmd.Args().Slice(), k.Strings("snapshot.restore_cmds"))
+			for _, name := range restored {
+				fmt.Printf("restored session %s\n", name)
+			}
+			if err != nil {
+				return exitError(fmt.Sprintf("error while restoring snapshot %s", file), err)
+			}
+			return nil
+		},
+	}
+}
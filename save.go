@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/snapshot"
+	"github.com/winter-again/flow/internal/tmux"
+	"github.com/winter-again/flow/internal/xdg"
+)
+
+func Save() *cli.Command {
+	socketName, socketPath := tmux.GetDefaultSocket()
+
+	return &cli.Command{
+		Name:                   "save",
+		Usage:                  "Save a snapshot of the tmux server's sessions, windows and panes",
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			server := flagServer(cmd, socketName, socketPath)
+
+			snap, err := snapshot.Take(ctx, server)
+			if err != nil {
+				return exitError(fmt.Sprintf("error while taking snapshot of server with socket path '%s'", server.SocketPath), err)
+			}
+
+			dir, err := snapshotDir()
+			if err != nil {
+				return cli.Exit(err, 1)
+			}
+			path, err := snapshot.Save(dir, snap, k.Int("snapshot.keep"))
+			if err != nil {
+				return cli.Exit(fmt.Errorf("error while saving snapshot: %w", err), 1)
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+}
+
+// snapshotDir returns the directory snapshots are saved to
+func snapshotDir() (string, error) {
+	state, err := xdg.StateHome()
+	if err != nil {
+		return "", fmt.Errorf("couldn't determine state directory: %w", err)
+	}
+	return filepath.Join(state, "flow", "snapshots"), nil
+}
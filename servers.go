@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/tmux"
+)
+
+func Servers() *cli.Command {
+	var clean bool
+	var format string
+
+	return &cli.Command{
+		Name:  "servers",
+		Usage: "List the tmux servers of the current user, including stale sockets",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "clean",
+				Usage:       "Remove the sockets of servers that aren't running anymore",
+				Destination: &clean,
+			},
+			&cli.StringFlag{
+				Name:        "format",
+				Aliases:     []string{"f"},
+				Value:       "table",
+				Usage:       "Output format: table, json or tsv",
+				Destination: &format,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			servers, err := tmux.ListServers()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("error while listing servers: %v", err), 1)
+			}
+
+			l := listing{header: []string{"NAME", "STATUS", "SESSIONS", "PATH"}}
+			for _, server := range servers {
+				item := probeServer(ctx, server)
+				if clean && item.Status == "stale" {
+					if err := os.Remove(server.SocketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+						return cli.Exit(fmt.Sprintf("error while removing stale socket: %v", err), 1)
+					}
+					fmt.Fprintf(os.Stderr, "removed stale socket %s\n", server.SocketPath)
+					continue
+				}
+				l.add(item, item.Name, item.Status, strconv.Itoa(item.Sessions), item.Path)
+			}
+
+			if err := l.write(os.Stdout, format); err != nil {
+				return cli.Exit(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+type serverItem struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Status   string `json:"status"` // "alive", "stale", "unresponsive" or "unknown"
+	Sessions int    `json:"sessions"`
+}
+
+// probeServer checks whether the server behind a socket is alive and, if so, how many sessions
+// it has. A socket that can't be probed, e.g. one of another user, is reported as unknown with
+// a warning, since one socket shouldn't keep the others from being listed
+func probeServer(ctx context.Context, server *tmux.Server) serverItem {
+	item := serverItem{Name: server.SocketName, Path: server.SocketPath, Status: "stale"}
+	alive, err := server.Alive(ctx)
+	if err != nil {
+		warn(err)
+		item.Status = "unknown"
+		return item
+	}
+	if !alive {
+		return item
+	}
+
+	sessions, err := server.GetSessions(ctx)
+	if errors.Is(err, tmux.ErrNoServer) {
+		// NOTE: the server exited in the meantime
+		return item
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		item.Status = "unresponsive"
+		return item
+	}
+	if err != nil {
+		warn(err)
+		item.Status = "unknown"
+		return item
+	}
+	item.Status = "alive"
+	item.Sessions = len(sessions)
+	return item
+}
@@ -18,40 +18,17 @@ func Start() *cli.Command {
 		// TODO: this seems to check exclusivity, but is the behavior correct? It prints the
 		// help and a warning under
 		// It could be a bug closed by this PR: https://github.com/urfave/cli/issues/2146
-		MutuallyExclusiveFlags: []cli.MutuallyExclusiveFlags{
-			{
-				Flags: [][]cli.Flag{
-					{
-						&cli.StringFlag{
-							Name:        "name",
-							Aliases:     []string{"n"},
-							Value:       socketName,
-							Usage:       "tmux server socket name",
-							Destination: &socketName,
-						},
-					},
-					{
-						&cli.StringFlag{
-							Name:        "path",
-							Aliases:     []string{"p"},
-							Value:       socketPath,
-							Usage:       "tmux server socket path",
-							Destination: &socketPath,
-						},
-					},
-				},
-			},
-		},
+		MutuallyExclusiveFlags: socketFlags(&socketName, &socketPath),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			server := tmux.NewServer(socketName, socketPath)
+			server := flagServer(cmd, socketName, socketPath)
 
-			_, _, err := server.Start()
+			_, _, err := server.Start(ctx)
 			if err != nil {
-				return cli.Exit(fmt.Errorf("error while starting server with socket name '%s' and socket path '%s': %w", server.SocketName, server.SocketPath, err), 1)
+				return exitError(fmt.Sprintf("error while starting server with socket name '%s' and socket path '%s'", server.SocketName, server.SocketPath), err)
 			}
-			_, _, err = server.Attach("")
+			_, _, err = server.Attach(ctx, "")
 			if err != nil {
-				return cli.Exit(fmt.Errorf("error while attaching to server with socket name '%s' and socket path '%s': %w", server.SocketName, server.SocketPath, err), 1)
+				return exitError(fmt.Sprintf("error while attaching to server with socket name '%s' and socket path '%s'", server.SocketName, server.SocketPath), err)
 			}
 			return nil
 		},
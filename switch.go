@@ -4,189 +4,295 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"os/exec"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/urfave/cli/v3"
+	"github.com/winter-again/flow/internal/history"
+	"github.com/winter-again/flow/internal/layout"
+	"github.com/winter-again/flow/internal/picker"
 	"github.com/winter-again/flow/internal/tmux"
 )
 
-const sessionSep = ": "
-
 func Switch() *cli.Command {
+	var all bool
+
 	return &cli.Command{
 		Name:  "switch",
 		Usage: "Switch tmux sessions using a popup",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "all",
+				Aliases:     []string{"a"},
+				Usage:       "Also pick sessions of the other running tmux servers, shown as <server>:<session>",
+				Destination: &all,
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			if !tmux.InsideTmux() {
-				return cli.Exit(errors.New("not running inside tmux"), 1)
+			server, err := tmux.GetCurrentServer()
+			if err != nil {
+				return exitError("error while finding current server", err)
+			}
+			current, err := server.GetCurrent(ctx)
+			if err != nil {
+				return exitError("error while finding current client", err)
 			}
 
-			// TODO: should cli.Exit() or handle these instead?
-			server, err := tmux.GetCurrentServer()
+			sessions, err := server.GetSessions(ctx)
 			if err != nil {
-				return err
+				return exitError("error while listing sessions", err)
 			}
 
-			sessions, err := server.GetSessions()
+			// NOTE: before picking, since the current session can be killed in the picker
+			from := currentVisit(server, current, sessions)
+
+			p, err := newPicker()
 			if err != nil {
-				return err
+				return cli.Exit(err, 1)
 			}
 
-			session, err := selectSession(sessions)
+			target, session, err := selectSession(ctx, p, server, sessions, all)
 			if err != nil {
-				// TODO: what was this?
-				if err == errFzfTmux {
+				if errors.Is(err, picker.ErrCancelled) {
 					return nil
 				}
-				return err
+				return exitError("error while selecting session", err)
 			}
 
-			if server.SessionExists(session.Name) {
-				if err := switchSess(session); err != nil {
-					return err
+			if target != server {
+				if err := server.MoveClient(ctx, current.Client, target, session.Name); err != nil {
+					return exitError("error while switching servers", err)
 				}
-			} else {
-				newSession, err := server.CreateSession(session.Name, session.Path)
+				recordVisit(from, visitTo(target, session), "")
+				return nil
+			}
+
+			var existing *tmux.Session
+			var project *layout.Project
+			if session.Path != "" {
+				// NOTE: a dir goes to the session already working in it, whatever its name,
+				// since sessions may have been killed in the picker this lists them again
+				sessions, err := server.GetSessions(ctx)
 				if err != nil {
-					return err
+					return exitError("error while listing sessions", err)
 				}
-
-				err = switchSess(newSession)
+				if project, err = projectFor(session.Path); err != nil {
+					return exitError("error while reading project", err)
+				}
+				var ok bool
+				session, ok, err = sessionForDir(ctx, sessions, session.Path, project)
 				if err != nil {
-					return err
+					return exitError("error while naming session", err)
+				}
+				if ok {
+					existing = session
+				}
+			} else if s, err := server.GetSession(ctx, session.Name); err == nil {
+				existing = s
+			}
+
+			if existing != nil {
+				if err := switchSess(ctx, server, current.Client, existing); err != nil {
+					return exitError("error while switching sessions", err)
 				}
+				recordVisit(from, visitTo(server, existing), "")
+				runSwitchHooks(ctx, existing)
+				return nil
 			}
+
+			newSession, err := createSession(ctx, server, session.Name, session.Path, project)
+			if err != nil {
+				return exitError("error while creating session", err)
+			}
+
+			err = switchSess(ctx, server, current.Client, newSession)
+			if err != nil {
+				return exitError("error while switching sessions", err)
+			}
+			recordVisit(from, visitTo(server, newSession), session.Path)
+			runSwitchHooks(ctx, newSession)
 			return nil
 		},
 	}
 }
 
-var errFzfTmux = errors.New("exited fzf-tmux")
+// newPicker returns the picker backend chosen in the config
+func newPicker() (picker.Picker, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find flow executable: %w", err)
+	}
 
-// selectSession handles the fzf-tmux window and session selection (and potentially creation)
-func selectSession(sessions []*tmux.Session) (*tmux.Session, error) {
-	// HACK: instead of relying on fd, flow defines its own command that it calls
-	// then populates fzf-tmux window with results
-	findCmd := "flow find"
+	// TODO: how do these interact with user's tmux settings? inherit?
+	return picker.New(k.String("flow.picker"), picker.Config{
+		Width:         k.String("fzf-tmux.width"),
+		Height:        k.String("fzf-tmux.length"),
+		Border:        k.String("fzf-tmux.border"),
+		PreviewBorder: k.String("fzf-tmux.preview_border"),
+		DmenuCmd:      k.Strings("dmenu.cmd"),
+		FlowCmd:       []string{exe, "pick"},
+	})
+}
 
-	// fdDirs := strings.Join(k.Strings("find.dirs"), " ")
-	// fdArgs := strings.Join(k.Strings("find.args"), " ")
-	// _ = fmt.Sprintf("fd . %s %s --type d", fdDirs, fdArgs)
+const pickerHeader = "\033[1;34m<tab>\033[m: common dirs / \033[1;34m<shift-tab>\033[m: sessions / \033[1;34m<ctrl-k>\033[m: kill session"
 
-	// TODO: how do these interact with user's tmux settings? inherit?
-	fzfTmuxWidth := k.String("fzf-tmux.width")
-	fzfTmuxLength := k.String("fzf-tmux.length")
-	fzfTmuxBorder := k.String("fzf-tmux.border")
-	fzfTmuxPrevCmd := strings.Join(k.Strings("fzf-tmux.preview_dir_cmd"), " ")
-	fzfTmuxPrevPos := k.String("fzf-tmux.preview_pos")
-	fzfTmuxPrevSize := k.String("fzf-tmux.preview_size")
-	fzfTmuxPrevBorder := k.String("fzf-tmux.preview_border")
-
-	n := len(sessions)
-	sessionList := make([]string, n)
-	pad := len(strconv.Itoa(n)) + len(sessionSep)
-	for i, session := range sessions {
-		idx := fmt.Sprintf("%-*v", pad, strconv.Itoa(i)+sessionSep)
-		sessionList[i] = idx + session.Name
-	}
-	sessionStr := strings.Join(sessionList, "\n")
-
-	args := []string{
-		"--layout",
-		"reverse",    // display from top; overrides user fzf config
-		"--no-multi", // disable multi-select
-		"-p",         // popup window size, req. tmux 3.2+
-		fmt.Sprintf("%s,%s", fzfTmuxWidth, fzfTmuxLength),
-		"--prompt",
-		"Sessions: ",
-		"--header",
-		// NOTE: hard-coded options
-		"\033[1;34m<tab>\033[m: common dirs / \033[1;34m<shift-tab>\033[m: sessions / \033[1;34m<ctrl-k>\033[m: kill session",
-		"--preview",
-		fmt.Sprintf("active_pane_id=$(tmux display-message -t {%d..} -p '#{pane_id}'); tmux capture-pane -ep -t $active_pane_id", 2),
-		"--bind",
-		// fmt.Sprintf("tab:reload(%s)+change-prompt( Common dirs: )+change-preview(%s {})+change-preview-label(Files)", fdCmd, fzfTmuxPrevCmdStr),
-		fmt.Sprintf("tab:reload(%s)+change-prompt(Common dirs: )+change-preview(%s {})+change-preview-label(Files)", findCmd, fzfTmuxPrevCmd),
-		"--bind",
-		fmt.Sprintf("shift-tab:reload(tmux list-sessions -F '#{line}: #{session_name}')+change-prompt(Sessions: )+change-preview(active_pane_id=$(tmux display-message -t {%d..} -p '#{pane_id}'); tmux capture-pane -ep -t $active_pane_id)+change-preview-label(Currently active pane)", 2),
-		"--bind",
-		fmt.Sprintf("ctrl-k:execute(tmux kill-session -t {%d..})+reload(tmux list-sessions -F '#{line}: #{session_name}')", 2),
-		"--preview-label",
-		"Currently active pane",
-		"--preview-window",
-		fmt.Sprintf("%s,%s,border-%s", fzfTmuxPrevPos, fzfTmuxPrevSize, fzfTmuxPrevBorder),
-		"--border",
-		fzfTmuxBorder,
-		"--no-separator",
-	}
-
-	fzfTmux, err := exec.LookPath("fzf-tmux") // NOTE: fzf-tmux is wrapper script from fzf
-	if err != nil {
-		return &tmux.Session{}, errors.New("couldn't find fzf-tmux in the PATH")
+// selectSession handles the picker window and session selection (and potentially creation),
+// switching between the session and directory lists until something is selected. Also
+// returns the server of the session, which is only another one if all is set
+func selectSession(ctx context.Context, p picker.Picker, server *tmux.Server, sessions []*tmux.Session, all bool) (*tmux.Server, *tmux.Session, error) {
+	// HACK: instead of relying on fd, flow lists the dirs itself, the same way `flow find` does
+	sessionPreview := "tmux capture-pane -ep -t ={}:"
+	if all {
+		// NOTE: sessions of other servers are previewed on their own socket
+		sessionPreview = `s={}; case "$s" in *:*) tmux -L "${s%%:*}" capture-pane -ep -t "=${s#*:}:" ;; *) tmux capture-pane -ep -t "=$s:" ;; esac`
 	}
-	fzfTmuxCmd := exec.Command(fzfTmux, args...)
+	sessionOpts := pickerOptions(p, "Sessions: ", sessionPreview, "Currently active pane")
+	dirOpts := pickerOptions(p, "Common dirs: ", strings.Join(k.Strings("fzf-tmux.preview_dir_cmd"), " ")+" {}", "Files")
 
-	stdin, err := fzfTmuxCmd.StdinPipe()
+	items, err := sessionItems(ctx, server, sessions, all)
 	if err != nil {
-		return &tmux.Session{}, fmt.Errorf("error creating stdin pipe for fzf-tmux: %w", err)
+		return nil, &tmux.Session{}, err
+	}
+	opts := sessionOpts
+	if !p.Actions() {
+		// NOTE: pickers without binds can't switch lists, so show everything at once
+		dirs, err := findDirs(ctx, false)
+		if err != nil {
+			return nil, &tmux.Session{}, err
+		}
+		items = append(items, dirs...)
+		opts.Preview = ""
 	}
 
-	go func() {
-		defer stdin.Close()
-		io.WriteString(stdin, sessionStr)
-	}()
+	for {
+		result, err := p.Pick(ctx, items, opts)
+		if err != nil {
+			return nil, &tmux.Session{}, err
+		}
 
-	out, err := fzfTmuxCmd.CombinedOutput()
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			// NOTE: 130 = ctrl-c or esc
-			if exitError.ExitCode() == 130 {
-				return &tmux.Session{}, errFzfTmux
+		switch result.Action {
+		case picker.ActionSelect:
+			target, session := sessionFromSelection(server, result.Selection)
+			return target, session, nil
+		case picker.ActionDirs:
+			items, err = findDirs(ctx, false)
+			if err != nil {
+				return nil, &tmux.Session{}, err
+			}
+			opts = dirOpts
+			continue
+		case picker.ActionKill:
+			// NOTE: only sessions can be killed; ignore the bind while listing dirs
+			if opts == dirOpts {
+				continue
+			}
+			if result.Selection != "" {
+				target, session := sessionFromSelection(server, result.Selection)
+				if err := target.KillSession(ctx, session.Name); err != nil {
+					return nil, &tmux.Session{}, err
+				}
 			}
-			return &tmux.Session{}, fmt.Errorf("error running fzf-tmux command: %w", err)
 		}
-		return &tmux.Session{}, fmt.Errorf("error running fzf-tmux command: %w", err)
+
+		sessions, err = server.GetSessions(ctx)
+		if err != nil {
+			return nil, &tmux.Session{}, err
+		}
+		items, err = sessionItems(ctx, server, sessions, all)
+		if err != nil {
+			return nil, &tmux.Session{}, err
+		}
+		opts = sessionOpts
 	}
+}
 
-	selection := cleanSessionName(string(out))
-	if tmux.IsValidPath(selection) {
-		return &tmux.Session{
-			Name: filepath.Base(selection),
-			Path: selection,
-		}, nil
+// pickerOptions returns the options for one of the picker's lists
+func pickerOptions(p picker.Picker, prompt string, preview string, previewLabel string) picker.Options {
+	size, err := strconv.Atoi(strings.TrimSuffix(k.String("fzf-tmux.preview_size"), "%"))
+	if err != nil {
+		size = 50
 	}
-	return &tmux.Session{
-		Name: selection,
-	}, nil
+	opts := picker.Options{
+		Prompt:       prompt,
+		Preview:      preview,
+		PreviewLabel: previewLabel,
+		PreviewPos:   k.String("fzf-tmux.preview_pos"),
+		PreviewSize:  size,
+	}
+	if p.Actions() {
+		// NOTE: hard-coded options
+		opts.Header = pickerHeader
+	}
+	return opts
 }
 
-// switchSess switches client to the specified tmux session
-func switchSess(session *tmux.Session) error {
-	// NOTE: prepending "=" to session name enforces only exact matches
-	sessionName := "=" + session.Name
-	args := []string{
-		"switch-client",
-		"-t",
-		sessionName,
+// sessionNames returns the names of the sessions in flow.sort order
+func sessionNames(sessions []*tmux.Session) []string {
+	names := make([]string, len(sessions))
+	for i, session := range sessions {
+		names[i] = session.Name
 	}
-	_, _, err := tmux.Cmd(args)
+	sortByHistory(history.Sessions, names)
+	return names
+}
+
+// sessionItems returns the names of the server's sessions in flow.sort order, followed
+// by the sessions of the other running servers as <server>:<session> if all is set
+func sessionItems(ctx context.Context, server *tmux.Server, sessions []*tmux.Session, all bool) ([]string, error) {
+	items := sessionNames(sessions)
+	if !all {
+		return items, nil
+	}
+
+	servers, err := tmux.ListServers()
 	if err != nil {
-		return fmt.Errorf("error switching tmux sessions: %w", err)
+		return []string{}, err
+	}
+	for _, other := range servers {
+		if other.SocketPath == server.SocketPath {
+			continue
+		}
+		if alive, err := other.Alive(ctx); err != nil || !alive {
+			continue
+		}
+		otherSessions, err := other.GetSessions(ctx)
+		if err != nil {
+			// NOTE: one stuck server shouldn't keep the others from being picked
+			warn(err)
+			continue
+		}
+		for _, name := range sessionNames(otherSessions) {
+			items = append(items, other.SocketName+":"+name)
+		}
 	}
-	return nil
+	return items, nil
 }
 
-func cleanSessionName(sessionName string) string {
-	s := strings.Split(sessionName, sessionSep)
-	var name string
-	if len(s) == 1 {
-		name = s[0]
-	} else {
-		name = s[1]
+// sessionFromSelection interprets a picked line as either a directory, left unnamed for
+// sessionForDir, the name of an existing session or <server>:<session> for a session
+// of another server. Returns the server of the session along with it
+func sessionFromSelection(server *tmux.Server, selection string) (*tmux.Server, *tmux.Session) {
+	// NOTE: dirs are listed with absolute paths, unlike names such as "work/api"
+	if filepath.IsAbs(selection) && tmux.IsValidPath(selection) {
+		return server, &tmux.Session{
+			Path: selection,
+		}
+	}
+	// NOTE: session names can't contain colons, so this can only be another server's
+	if socketName, sessionName, ok := strings.Cut(selection, ":"); ok {
+		_, defaultSocketPath := tmux.GetDefaultSocket()
+		return tmux.NewServer(socketName, defaultSocketPath), &tmux.Session{
+			Name: sessionName,
+		}
 	}
-	return strings.TrimSpace(name)
+	return server, &tmux.Session{
+		Name: selection,
+	}
+}
+
+// switchSess switches the client to the specified tmux session
+func switchSess(ctx context.Context, server *tmux.Server, client string, session *tmux.Session) error {
+	return server.SwitchClient(ctx, client, session.Name)
 }